@@ -2,10 +2,10 @@ package cmd
 
 import (
 	"fmt"
-	"github.com/SHCDevelops/file-manager/internal/filesystem"
+	"github.com/SHCDevelops/file-manager/lib/utils"
+	"github.com/SHCDevelops/file-manager/pkg/filemanager"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
-	"strings"
 )
 
 var AnalyzeSpaceCmd = &cobra.Command{
@@ -13,38 +13,88 @@ var AnalyzeSpaceCmd = &cobra.Command{
 	Short: "Analyze disk space usage in the specified directory",
 	Long:  `This command analyzes disk space usage and shows the largest files.`,
 	Args:  cobra.MinimumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		directory := args[0]
 
 		top, _ := cmd.Flags().GetInt("top")
-		ignorePattern, _ := cmd.Flags().GetString("ignore")
-		ignoreList := strings.Split(ignorePattern, ",")
+		var sizeLimit int64 = -1
+		if limit, _ := cmd.Flags().GetString("fail-if-larger-than"); limit != "" {
+			parsed, err := utils.ParseSize(limit)
+			if err != nil {
+				return err
+			}
+			sizeLimit = parsed
+		}
+
+		opts, stopProgress, err := scanOptions(cmd)
+		if err != nil {
+			return err
+		}
 
-		files, err := filesystem.AnalyzeSpace(directory, top, ignoreList)
+		if watchMode, _ := cmd.Flags().GetBool("watch"); watchMode {
+			var files []filemanager.FileSize
+			update := watchPrinter(stopProgress, func() { printTopFiles(files) })
+			err := filemanager.WatchSpace(cmd.Context(), directory, top, func(updated []filemanager.FileSize) {
+				files = updated
+				update()
+			}, watchOptions(opts)...)
+			return watchResult(err)
+		}
+
+		// Проверке размера нужен самый большой файл всего обхода, даже если
+		// --top ничего не показывает.
+		files, err := filemanager.AnalyzeSpace(cmd.Context(), directory, max(top, 1), opts...)
+		stopProgress()
+
+		warnings, err := scanWarnings(err)
+		defer printWarnings(warnings)
+		if err != nil && !partial(err) {
+			return err
+		}
+		var largest filemanager.FileSize
+		if len(files) > 0 {
+			// Файлы отсортированы по убыванию.
+			largest = files[0]
+		}
+		if top > 0 {
+			printTopFiles(files[:min(len(files), top)])
+		}
 
 		if err != nil {
-			color.Red("Error: %v\n", err)
-			return
-		}
-
-		if len(files) == 0 {
-			color.Yellow("No files found.")
-		} else {
-			header := color.New(color.FgHiCyan, color.Bold).SprintFunc()
-			pathColor := color.New(color.FgHiWhite).SprintFunc()
-			sizeColor := color.New(color.FgHiGreen).SprintFunc()
-
-			fmt.Printf("\n%s\n", header("Top files by size:"))
-			for _, file := range files {
-				fmt.Printf("▸ %s %s\n",
-					pathColor(file.Path),
-					sizeColor(fmt.Sprintf("(%d bytes)", file.Size)))
-			}
+			return err
+		}
+
+		if sizeLimit >= 0 && largest.Size > sizeLimit {
+			return fmt.Errorf("%w: %s is %d bytes, larger than %d", ErrFindings, largest.Path, largest.Size, sizeLimit)
 		}
+		return nil
 	},
 }
 
+func printTopFiles(files []filemanager.FileSize) {
+	if len(files) == 0 {
+		color.Yellow("No files found.")
+		return
+	}
+
+	header := color.New(color.FgHiCyan, color.Bold).SprintFunc()
+	pathColor := color.New(color.FgHiWhite).SprintFunc()
+	sizeColor := color.New(color.FgHiGreen).SprintFunc()
+
+	fmt.Printf("\n%s\n", header("Top files by size:"))
+	for _, file := range files {
+		fmt.Printf("▸ %s %s\n",
+			pathColor(file.Path),
+			sizeColor(fmt.Sprintf("(%d bytes)", file.Size)))
+	}
+}
+
 func init() {
 	AnalyzeSpaceCmd.Flags().IntP("top", "t", 10, "Number of files to display")
 	AnalyzeSpaceCmd.Flags().StringP("ignore", "i", "", "Comma-separated list of directories or patterns to ignore (e.g., temp,.git)")
+	AnalyzeSpaceCmd.Flags().String("fail-if-larger-than", "", "Exit with code 1 if any file is larger than the given size (e.g., 100M)")
+	AnalyzeSpaceCmd.Flags().String("progress", "auto", progressFlagUsage)
+	AnalyzeSpaceCmd.Flags().Bool("watch", false, watchFlagUsage)
+	AnalyzeSpaceCmd.Flags().Bool("strict", false, "Stop on the first unreadable entry instead of skipping it")
+	AnalyzeSpaceCmd.MarkFlagsMutuallyExclusive("watch", "fail-if-larger-than")
 }
@@ -2,9 +2,11 @@ package cmd
 
 import (
 	"fmt"
-	"github.com/SHCDevelops/file-manager/internal/filesystem"
+	"github.com/SHCDevelops/file-manager/pkg/filemanager"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -14,55 +16,243 @@ var CodeStatsCmd = &cobra.Command{
 	Long: `This command analyzes code statistics including:
 - Total lines of code
 - Comment lines
-- Code lines (total - comments)
+- Blank lines
+- Code lines (total - comments - blank)
 
-Supports multiple languages. Use --ignore-language to exclude specific languages.`,
+Supports multiple languages. Use --ignore-language to exclude specific languages.
+
+<script> and <style> blocks in HTML, Vue and Svelte files, fenced code blocks in Markdown
+and code cells in Jupyter notebooks are counted as their own languages.
+
+Generated files (a "Code generated ... DO NOT EDIT." header, *.pb.go, *.min.js,
+package-lock.json and pnpm-lock.yaml, minified code) and vendored code (vendor/, node_modules/, third_party/) are reported
+separately; use --include-generated and --include-vendored to count them as code.`,
 	Args: cobra.MinimumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		directory := args[0]
 
-		ignorePattern, _ := cmd.Flags().GetString("ignore")
-		ignoreList := strings.Split(ignorePattern, ",")
-
-		ignoreLanguagePattern, _ := cmd.Flags().GetString("ignore-language")
-		ignoreLanguages := strings.Split(strings.ToLower(ignoreLanguagePattern), ",")
+		complexity, _ := cmd.Flags().GetBool("complexity")
+		top, _ := cmd.Flags().GetInt("top")
+		if !complexity {
+			top = 0
+		}
+		useGit, _ := cmd.Flags().GetBool("git")
+		byAuthor, _ := cmd.Flags().GetBool("by-author")
 
-		stats, err := filesystem.CountCodeLines(directory, ignoreList, ignoreLanguages)
+		var include func(string, bool) bool
+		if useGit || byAuthor {
+			tracked, err := filemanager.GitTracked(cmd.Context(), directory)
+			if err != nil {
+				return err
+			}
+			include = tracked
+		}
 
+		opts, stopProgress, err := scanOptions(cmd)
 		if err != nil {
-			color.Red("Error: %v\n", err)
-			return
+			return err
+		}
+		opts = append(opts, codeOptions(cmd)...)
+		opts = append(opts, filemanager.WithComplexity(complexity))
+		if include != nil {
+			opts = append(opts, filemanager.WithInclude(include))
 		}
 
-		if len(stats.Languages) == 0 {
-			color.Yellow("No code files found in supported formats")
-			return
+		if watchMode, _ := cmd.Flags().GetBool("watch"); watchMode {
+			var stats *filemanager.CodeStats
+			update := watchPrinter(stopProgress, func() { printCodeStats(stats, top) })
+			err := filemanager.WatchCodeStats(cmd.Context(), directory, func(updated *filemanager.CodeStats) {
+				stats = updated
+				update()
+			}, watchOptions(opts)...)
+			return watchResult(err)
 		}
 
-		header := color.New(color.FgHiMagenta, color.Bold).SprintFunc()
-		langHeader := color.New(color.FgHiCyan, color.Underline).SprintFunc()
-		highlight := color.New(color.FgHiYellow).SprintFunc()
+		stats, err := filemanager.CountCodeLines(cmd.Context(), directory, opts...)
+		stopProgress()
 
-		fmt.Printf("\n%s\n", header("Code Statistics:"))
-		for lang, data := range stats.Languages {
-			if data.TotalLines == 0 {
-				continue
-			}
-			fmt.Printf("\n%s\n", langHeader(lang+":"))
-			fmt.Printf("  Total lines: %s\n", highlight(data.TotalLines))
-			fmt.Printf("  Comments:    %s %s\n",
-				highlight(data.CommentLines),
-				color.HiBlackString("(%.1f%%)", percent(data.CommentLines, data.TotalLines)))
-			fmt.Printf("  Code lines:  %s %s\n",
-				highlight(data.CodeLines),
-				color.HiBlackString("(%.1f%%)", percent(data.CodeLines, data.TotalLines)))
+		warnings, err := scanWarnings(err)
+		defer func() { printWarnings(warnings) }()
+		if err != nil && !partial(err) {
+			return err
+		}
+
+		printCodeStats(stats, top)
+		if err != nil || !byAuthor || len(stats.Files) == 0 {
+			return err
 		}
+
+		strict, _ := cmd.Flags().GetBool("strict")
+		authors, err := filemanager.CountAuthors(cmd.Context(), directory, stats, filemanager.WithStrict(strict))
+		blameWarnings, err := scanWarnings(err)
+		warnings = append(warnings, blameWarnings...)
+		if err != nil && !partial(err) {
+			return err
+		}
+		printAuthors(authors)
+		return err
 	},
 }
 
+func printAuthors(authors []filemanager.AuthorStat) {
+	header := color.New(color.FgHiMagenta, color.Bold).SprintFunc()
+	nameColor := color.New(color.FgHiCyan).SprintFunc()
+	highlight := color.New(color.FgHiYellow).SprintFunc()
+
+	total := 0
+	for _, a := range authors {
+		total += a.Lines
+	}
+
+	fmt.Printf("\n%s\n", header("Lines by author:"))
+	for _, a := range authors {
+		languages := make([]string, 0, len(a.Languages))
+		for lang := range a.Languages {
+			languages = append(languages, lang)
+		}
+		sort.Slice(languages, func(i, j int) bool {
+			if a.Languages[languages[i]] != a.Languages[languages[j]] {
+				return a.Languages[languages[i]] > a.Languages[languages[j]]
+			}
+			return languages[i] < languages[j]
+		})
+		parts := make([]string, len(languages))
+		for i, lang := range languages {
+			parts[i] = fmt.Sprintf("%s %d", lang, a.Languages[lang])
+		}
+
+		fmt.Printf("▸ %s %s %s %s\n",
+			nameColor(a.Author),
+			highlight(a.Lines),
+			color.HiBlackString("(%.1f%%)", percent(a.Lines, total)),
+			color.HiBlackString(strings.Join(parts, ", ")))
+	}
+}
+
+// printCodeStats печатает статистику по языкам и, если top > 0,
+// top самых сложных функций.
+func printCodeStats(stats *filemanager.CodeStats, top int) {
+	defer printExcluded(stats.Excluded)
+	if len(stats.Languages) == 0 {
+		color.Yellow("No code files found in supported formats")
+		return
+	}
+
+	header := color.New(color.FgHiMagenta, color.Bold).SprintFunc()
+	langHeader := color.New(color.FgHiCyan, color.Underline).SprintFunc()
+	highlight := color.New(color.FgHiYellow).SprintFunc()
+
+	fmt.Printf("\n%s\n", header("Code Statistics:"))
+	for lang, data := range stats.Languages {
+		if data.TotalLines == 0 {
+			continue
+		}
+		fmt.Printf("\n%s\n", langHeader(lang+":"))
+		fmt.Printf("  Total lines: %s\n", highlight(data.TotalLines))
+		fmt.Printf("  Comments:    %s %s\n",
+			highlight(data.CommentLines),
+			color.HiBlackString("(%.1f%%)", percent(data.CommentLines, data.TotalLines)))
+		fmt.Printf("  Blank lines: %s %s\n",
+			highlight(data.BlankLines),
+			color.HiBlackString("(%.1f%%)", percent(data.BlankLines, data.TotalLines)))
+		fmt.Printf("  Code lines:  %s %s\n",
+			highlight(data.CodeLines),
+			color.HiBlackString("(%.1f%%)", percent(data.CodeLines, data.TotalLines)))
+		if data.Functions > 0 || data.Branches > 0 {
+			printComplexity(data)
+		}
+	}
+
+	if files := stats.TopFiles(top); len(files) > 0 {
+		fmt.Printf("\n%s\n", header("Complexity hot spots:"))
+		for _, f := range files {
+			fmt.Printf("▸ %s %s %s\n",
+				highlight(f.Complexity),
+				f.Path,
+				color.HiBlackString("(%s, %d functions, %d branches, nesting %d)", f.Language, f.LanguageStat.Functions, f.Branches, f.MaxNesting))
+		}
+	}
+	if functions := stats.TopFunctions(top); len(functions) > 0 {
+		fmt.Printf("\n%s\n", header("Most complex functions:"))
+		for _, fn := range functions {
+			fmt.Printf("▸ %s %s %s\n",
+				highlight(fn.Complexity),
+				fn.Name,
+				color.HiBlackString("(%s:%d, nesting %d)", fn.Path, fn.Line, fn.Nesting))
+		}
+	}
+}
+
+// printComplexity печатает метрики сложности языка и гистограмму отступов.
+func printComplexity(data *filemanager.LanguageStat) {
+	highlight := color.New(color.FgHiYellow).SprintFunc()
+
+	var details []string
+	if data.Functions > 0 {
+		details = append(details, fmt.Sprintf("avg complexity %.1f", float64(data.Complexity)/float64(data.Functions)))
+	}
+	if data.MaxComplexity > 0 {
+		details = append(details, fmt.Sprintf("max %d", data.MaxComplexity))
+	}
+	if len(details) > 0 {
+		fmt.Printf("  Functions:   %s %s\n", highlight(data.Functions), color.HiBlackString("(%s)", strings.Join(details, ", ")))
+	} else {
+		fmt.Printf("  Functions:   %s\n", highlight(data.Functions))
+	}
+	fmt.Printf("  Branches:    %s %s\n", highlight(data.Branches), color.HiBlackString("(max nesting %d)", data.MaxNesting))
+
+	last := 0
+	for level, n := range data.Indentation {
+		if n > 0 {
+			last = level
+		}
+	}
+	levels := make([]string, 0, last+1)
+	for level, n := range data.Indentation[:last+1] {
+		label := strconv.Itoa(level)
+		if level == len(data.Indentation)-1 {
+			label += "+"
+		}
+		levels = append(levels, fmt.Sprintf("%s:%d", label, n))
+	}
+	fmt.Printf("  Indentation: %s\n", color.HiBlackString(strings.Join(levels, " ")))
+}
+
+// printExcluded печатает сводку по сгенерированным и сторонним файлам,
+// которые не вошли в статистику.
+func printExcluded(excluded map[filemanager.FileClass]*filemanager.ClassStat) {
+	if len(excluded) == 0 {
+		return
+	}
+	header := color.New(color.FgHiMagenta, color.Bold).SprintFunc()
+	highlight := color.New(color.FgHiYellow).SprintFunc()
+
+	fmt.Printf("\n%s\n", header("Not counted:"))
+	for _, class := range []filemanager.FileClass{filemanager.ClassGenerated, filemanager.ClassVendored} {
+		data, ok := excluded[class]
+		if !ok {
+			continue
+		}
+		fmt.Printf("  %-10s %s files, %s lines %s\n", string(class)+":",
+			highlight(data.Files), highlight(data.TotalLines),
+			color.HiBlackString("(use --include-%s to count)", class))
+	}
+}
+
 func init() {
 	CodeStatsCmd.Flags().StringP("ignore", "i", "", "Comma-separated list of directories or patterns to ignore")
 	CodeStatsCmd.Flags().StringP("ignore-language", "l", "", "Comma-separated list of languages to ignore")
+	CodeStatsCmd.Flags().Bool("include-generated", false, includeGeneratedFlagUsage)
+	CodeStatsCmd.Flags().Bool("include-vendored", false, includeVendoredFlagUsage)
+	CodeStatsCmd.Flags().String("progress", "auto", progressFlagUsage)
+	CodeStatsCmd.Flags().Bool("archives", false, archivesFlagUsage)
+	CodeStatsCmd.Flags().Bool("complexity", false, "Report function counts, complexity and nesting depth (exact for Go, estimated for other languages)")
+	CodeStatsCmd.Flags().IntP("top", "t", 10, "Number of most complex files and functions to display with --complexity")
+	CodeStatsCmd.Flags().Bool("git", false, "Only count files tracked in the git repository")
+	CodeStatsCmd.Flags().Bool("by-author", false, "Also attribute lines to authors using git blame (implies --git)")
+	CodeStatsCmd.Flags().Bool("watch", false, watchFlagUsage)
+	CodeStatsCmd.Flags().Bool("strict", false, "Stop on the first unreadable file instead of skipping it")
+	CodeStatsCmd.MarkFlagsMutuallyExclusive("watch", "by-author")
 }
 
 func percent(part, total int) float64 {
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/SHCDevelops/file-manager/pkg/filemanager"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var CodeStatsDiffCmd = &cobra.Command{
+	Use:   "diff [old] [new]",
+	Short: "Compare code statistics of two directories or git revisions",
+	Long: `This command counts code lines in two trees and reports how code, comment and blank
+lines changed per language and per file. Each argument is a directory or, if no such
+directory exists, a git revision (commit, branch or tag) of the repository given by --repo:
+
+  file-manager code-stats diff main HEAD
+  file-manager code-stats diff ./release-1.0 ./release-2.0`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, _ := cmd.Flags().GetString("repo")
+
+		opts, stopProgress, err := scanOptions(cmd)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, codeOptions(cmd)...)
+
+		var warnings filemanager.ScanErrors
+		defer func() { printWarnings(warnings) }()
+
+		trees := make([]*filemanager.CodeStats, len(args))
+		for i, arg := range args {
+			var stats *filemanager.CodeStats
+			if info, statErr := os.Stat(arg); statErr == nil && info.IsDir() {
+				stats, err = filemanager.CountCodeLines(cmd.Context(), arg, opts...)
+			} else {
+				stats, err = filemanager.CountCodeLinesAt(cmd.Context(), repo, arg, opts...)
+			}
+
+			var treeWarnings filemanager.ScanErrors
+			treeWarnings, err = scanWarnings(err)
+			warnings = append(warnings, treeWarnings...)
+			if err != nil {
+				// Сравнение с неполным деревом выглядело бы как удаление кода.
+				stopProgress()
+				return err
+			}
+			trees[i] = stats
+		}
+		stopProgress()
+
+		top, _ := cmd.Flags().GetInt("top")
+		printCodeStatsDiff(filemanager.DiffCodeStats(trees[0], trees[1]), top)
+		return nil
+	},
+}
+
+func printCodeStatsDiff(diff *filemanager.CodeStatsDiff, top int) {
+	if len(diff.Languages) == 0 && len(diff.Files) == 0 {
+		color.Green("No changes in code statistics.")
+		return
+	}
+
+	header := color.New(color.FgHiMagenta, color.Bold).SprintFunc()
+	langHeader := color.New(color.FgHiCyan).SprintFunc()
+	added := color.New(color.FgHiGreen).SprintFunc()
+	removed := color.New(color.FgHiRed).SprintFunc()
+	changed := color.New(color.FgHiYellow).SprintFunc()
+
+	signed := func(n int) string {
+		if n < 0 {
+			return removed(n)
+		}
+		if n > 0 {
+			return added(fmt.Sprintf("+%d", n))
+		}
+		return color.HiBlackString("0")
+	}
+
+	fmt.Printf("\n%s\n", header("Code Statistics Diff:"))
+	for _, d := range diff.Languages {
+		delta := d.Delta()
+		fmt.Printf("\n%s\n", langHeader(d.Language+":"))
+		fmt.Printf("  Code lines:  %s %s\n", signed(delta.CodeLines),
+			color.HiBlackString("(%d → %d)", d.Old.CodeLines, d.New.CodeLines))
+		fmt.Printf("  Comments:    %s %s\n", signed(delta.CommentLines),
+			color.HiBlackString("(%d → %d)", d.Old.CommentLines, d.New.CommentLines))
+		fmt.Printf("  Blank lines: %s %s\n", signed(delta.BlankLines),
+			color.HiBlackString("(%d → %d)", d.Old.BlankLines, d.New.BlankLines))
+	}
+
+	if len(diff.Files) == 0 || top <= 0 {
+		return
+	}
+	fmt.Printf("\n%s\n", header(fmt.Sprintf("Changed files (%d):", len(diff.Files))))
+	for i, f := range diff.Files {
+		if i == top {
+			fmt.Printf("... and %d more\n", len(diff.Files)-top)
+			break
+		}
+		path := changed(f.Path)
+		switch {
+		case f.Added:
+			path = added(f.Path)
+		case f.Removed:
+			path = removed(f.Path)
+		}
+		lang := f.Language
+		if f.Embedded {
+			lang += ", embedded"
+		}
+		fmt.Printf("▸ %s %s %s\n", path, signed(f.Delta().CodeLines), color.HiBlackString("(%s)", lang))
+	}
+}
+
+func init() {
+	CodeStatsDiffCmd.Flags().String("repo", ".", "Git repository (or a directory inside it) for arguments that are revisions")
+	CodeStatsDiffCmd.Flags().IntP("top", "t", 10, "Number of files with the largest change in code lines to display")
+	CodeStatsDiffCmd.Flags().StringP("ignore", "i", "", "Comma-separated list of directories or patterns to ignore")
+	CodeStatsDiffCmd.Flags().StringP("ignore-language", "l", "", "Comma-separated list of languages to ignore")
+	CodeStatsDiffCmd.Flags().Bool("include-generated", false, includeGeneratedFlagUsage)
+	CodeStatsDiffCmd.Flags().Bool("include-vendored", false, includeVendoredFlagUsage)
+	CodeStatsDiffCmd.Flags().String("progress", "auto", progressFlagUsage)
+	CodeStatsDiffCmd.Flags().Bool("strict", false, "Stop on the first unreadable file instead of skipping it")
+	CodeStatsCmd.AddCommand(CodeStatsDiffCmd)
+}
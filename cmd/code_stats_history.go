@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/SHCDevelops/file-manager/pkg/filemanager"
+	"github.com/spf13/cobra"
+)
+
+var CodeStatsHistoryCmd = &cobra.Command{
+	Use:   "history [directory]",
+	Short: "Count code lines in every commit of a git repository",
+	Long: `This command walks the commits of a local git repository (following the first parent,
+oldest first) and counts code, comment and blank lines per language in each commit's tree.
+The result is a time series suitable for charting codebase growth.
+
+Only files inside the given directory are counted. Unchanged files are parsed once.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		directory := args[0]
+
+		format, _ := cmd.Flags().GetString("format")
+		if format != "csv" && format != "json" {
+			return fmt.Errorf("invalid --format value %q, expected csv or json", format)
+		}
+		var history filemanager.HistoryOptions
+		history.Range, _ = cmd.Flags().GetString("range")
+		history.Since, _ = cmd.Flags().GetString("since")
+		history.MaxCount, _ = cmd.Flags().GetInt("max-count")
+
+		opts, stopProgress, err := scanOptions(cmd)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, codeOptions(cmd)...)
+
+		revisions, err := filemanager.CodeStatsHistory(cmd.Context(), directory, history, opts...)
+		stopProgress()
+
+		warnings, err := scanWarnings(err)
+		defer printWarnings(warnings)
+		if err != nil && !partial(err) {
+			return err
+		}
+
+		if format == "json" {
+			if writeErr := writeHistoryJSON(os.Stdout, revisions); writeErr != nil {
+				return writeErr
+			}
+			return err
+		}
+		if writeErr := writeHistoryCSV(os.Stdout, revisions); writeErr != nil {
+			return writeErr
+		}
+		return err
+	},
+}
+
+// writeHistoryCSV печатает одну строку на язык в каждом коммите.
+func writeHistoryCSV(w io.Writer, revisions []filemanager.Revision) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"commit", "date", "language", "code", "comments", "blank", "total"})
+	for _, rev := range revisions {
+		for _, lang := range sortedLanguages(rev.Languages) {
+			stat := rev.Languages[lang]
+			out.Write([]string{
+				rev.Commit,
+				rev.Date.Format(time.RFC3339),
+				lang,
+				strconv.Itoa(stat.CodeLines),
+				strconv.Itoa(stat.CommentLines),
+				strconv.Itoa(stat.BlankLines),
+				strconv.Itoa(stat.TotalLines),
+			})
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+type historyLanguage struct {
+	Code     int `json:"code"`
+	Comments int `json:"comments"`
+	Blank    int `json:"blank"`
+	Total    int `json:"total"`
+}
+
+type historyRevision struct {
+	Commit    string                     `json:"commit"`
+	Date      time.Time                  `json:"date"`
+	Subject   string                     `json:"subject"`
+	Languages map[string]historyLanguage `json:"languages"`
+}
+
+func writeHistoryJSON(w io.Writer, revisions []filemanager.Revision) error {
+	result := make([]historyRevision, len(revisions))
+	for i, rev := range revisions {
+		result[i] = historyRevision{
+			Commit:    rev.Commit,
+			Date:      rev.Date,
+			Subject:   rev.Subject,
+			Languages: make(map[string]historyLanguage, len(rev.Languages)),
+		}
+		for lang, stat := range rev.Languages {
+			result[i].Languages[lang] = historyLanguage{
+				Code:     stat.CodeLines,
+				Comments: stat.CommentLines,
+				Blank:    stat.BlankLines,
+				Total:    stat.TotalLines,
+			}
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+func sortedLanguages(languages map[string]*filemanager.LanguageStat) []string {
+	names := make([]string, 0, len(languages))
+	for lang := range languages {
+		names = append(names, lang)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	CodeStatsHistoryCmd.Flags().String("range", "HEAD", "Revision range to walk, e.g. v1.0..HEAD")
+	CodeStatsHistoryCmd.Flags().String("since", "", "Only commits newer than the date, e.g. 2024-01-01 or \"6 months ago\"")
+	CodeStatsHistoryCmd.Flags().Int("max-count", 0, "Only the last N commits of the range (0 for all)")
+	CodeStatsHistoryCmd.Flags().String("format", "csv", "Output format: csv or json")
+	CodeStatsHistoryCmd.Flags().StringP("ignore", "i", "", "Comma-separated list of directories or patterns to ignore")
+	CodeStatsHistoryCmd.Flags().StringP("ignore-language", "l", "", "Comma-separated list of languages to ignore")
+	CodeStatsHistoryCmd.Flags().Bool("include-generated", false, includeGeneratedFlagUsage)
+	CodeStatsHistoryCmd.Flags().Bool("include-vendored", false, includeVendoredFlagUsage)
+	CodeStatsHistoryCmd.Flags().String("progress", "auto", progressFlagUsage)
+	CodeStatsHistoryCmd.Flags().Bool("strict", false, "Stop on the first unreadable file instead of skipping it")
+	CodeStatsCmd.AddCommand(CodeStatsHistoryCmd)
+}
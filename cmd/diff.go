@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/SHCDevelops/file-manager/lib/utils"
+	"github.com/SHCDevelops/file-manager/pkg/filemanager"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var DiffCmd = &cobra.Command{
+	Use:   "diff [snapshot] [snapshot|directory]",
+	Short: "Compare a snapshot with another snapshot or the live directory tree",
+	Long: `This command reports files added, removed, modified and moved between two snapshots,
+and how the size of each directory changed. If the second argument is a directory or is
+omitted, the snapshot is compared with the current state of the directory (by default the
+one the snapshot was taken of), scanned with the same ignore patterns and hash algorithm.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		before, err := readSnapshotFile(args[0])
+		if err != nil {
+			return err
+		}
+
+		target := before.Root
+		if len(args) == 2 {
+			target = args[1]
+		}
+		info, err := os.Stat(target)
+		if err != nil {
+			return err
+		}
+
+		var after *filemanager.Snapshot
+		var warnings filemanager.ScanErrors
+		if info.IsDir() {
+			opts, stopProgress, err := scanOptions(cmd)
+			if err != nil {
+				return err
+			}
+			opts = append(opts,
+				filemanager.WithIgnore(before.IgnoreList...),
+				filemanager.WithArchives(before.Archives),
+				filemanager.WithHashAlgorithm(before.HashAlgorithm),
+			)
+
+			after, err = filemanager.TakeSnapshot(cmd.Context(), target, opts...)
+			stopProgress()
+
+			warnings, err = scanWarnings(err)
+			defer printWarnings(warnings)
+			if err != nil {
+				return err
+			}
+		} else if after, err = readSnapshotFile(target); err != nil {
+			return err
+		}
+
+		diff, err := filemanager.DiffSnapshots(before, after)
+		if err != nil {
+			return err
+		}
+
+		top, _ := cmd.Flags().GetInt("top")
+		printSnapshotDiff(diff, top)
+
+		if failOnChanges, _ := cmd.Flags().GetBool("fail-on-changes"); failOnChanges && !diff.Empty() {
+			return fmt.Errorf("%w: %d added, %d removed, %d modified, %d moved", ErrFindings,
+				len(diff.Added), len(diff.Removed), len(diff.Modified), len(diff.Moved))
+		}
+		return nil
+	},
+}
+
+func printSnapshotDiff(diff *filemanager.SnapshotDiff, top int) {
+	if diff.Empty() {
+		color.Green("No changes.")
+		return
+	}
+
+	header := color.New(color.FgHiCyan, color.Bold).SprintFunc()
+	added := color.New(color.FgHiGreen).SprintFunc()
+	removed := color.New(color.FgHiRed).SprintFunc()
+	changed := color.New(color.FgHiYellow).SprintFunc()
+	sizeColor := color.New(color.FgHiBlack).SprintFunc()
+
+	if len(diff.Added) > 0 {
+		fmt.Printf("\n%s\n", header(fmt.Sprintf("Added (%d):", len(diff.Added))))
+		for _, e := range diff.Added {
+			fmt.Printf("▸ %s %s\n", added(e.Path), sizeColor("("+utils.FormatSize(e.Size)+")"))
+		}
+	}
+	if len(diff.Removed) > 0 {
+		fmt.Printf("\n%s\n", header(fmt.Sprintf("Removed (%d):", len(diff.Removed))))
+		for _, e := range diff.Removed {
+			fmt.Printf("▸ %s %s\n", removed(e.Path), sizeColor("("+utils.FormatSize(e.Size)+")"))
+		}
+	}
+	if len(diff.Modified) > 0 {
+		fmt.Printf("\n%s\n", header(fmt.Sprintf("Modified (%d):", len(diff.Modified))))
+		for _, c := range diff.Modified {
+			fmt.Printf("▸ %s %s\n", changed(c.New.Path),
+				sizeColor(fmt.Sprintf("(%s → %s)", utils.FormatSize(c.Old.Size), utils.FormatSize(c.New.Size))))
+		}
+	}
+	if len(diff.Moved) > 0 {
+		fmt.Printf("\n%s\n", header(fmt.Sprintf("Moved (%d):", len(diff.Moved))))
+		for _, c := range diff.Moved {
+			fmt.Printf("▸ %s → %s\n", changed(c.Old.Path), changed(c.New.Path))
+		}
+	}
+
+	if len(diff.Dirs) > 0 && top > 0 {
+		fmt.Printf("\n%s\n", header("Directory size changes:"))
+		for i, d := range diff.Dirs {
+			if i == top {
+				fmt.Printf("... and %d more\n", len(diff.Dirs)-top)
+				break
+			}
+			delta := added("+" + utils.FormatSize(d.Delta()))
+			if d.Delta() < 0 {
+				delta = removed("-" + utils.FormatSize(-d.Delta()))
+			}
+			fmt.Printf("▸ %s %s %s\n", d.Path, delta,
+				sizeColor(fmt.Sprintf("(%s → %s)", utils.FormatSize(d.OldSize), utils.FormatSize(d.NewSize))))
+		}
+	}
+}
+
+func init() {
+	DiffCmd.Flags().IntP("top", "t", 10, "Number of directories with the largest size change to display")
+	DiffCmd.Flags().Bool("fail-on-changes", false, "Exit with code 1 if anything changed")
+	DiffCmd.Flags().String("progress", "auto", progressFlagUsage)
+	DiffCmd.Flags().Bool("strict", false, "Stop on the first unreadable entry instead of skipping it")
+}
@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// maxBatchArgsLen ограничивает суммарную длину путей в одном вызове --exec-batch,
+// чтобы не упереться в ARG_MAX.
+const maxBatchArgsLen = 128 * 1024
+
+// commandTemplate — команда для --exec/--exec-batch с плейсхолдерами в духе fd:
+// {} путь, {/} имя файла, {//} родительская директория,
+// {.} путь без расширения, {/.} имя файла без расширения.
+type commandTemplate struct {
+	args []string
+}
+
+var placeholders = []string{"{/.}", "{//}", "{/}", "{.}", "{}"}
+
+func parseCommandTemplate(command string) (*commandTemplate, error) {
+	args, err := splitCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, errors.New("empty command")
+	}
+
+	found := false
+	for _, arg := range args[1:] {
+		found = found || hasPlaceholder(arg)
+	}
+	if !found {
+		args = append(args, "{}")
+	}
+	return &commandTemplate{args: args}, nil
+}
+
+func hasPlaceholder(arg string) bool {
+	for _, p := range placeholders {
+		if strings.Contains(arg, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// command подставляет путь во все аргументы.
+func (t *commandTemplate) command(path string) *exec.Cmd {
+	args := make([]string, len(t.args))
+	for i, arg := range t.args {
+		args[i] = expandPlaceholders(arg, path)
+	}
+	return exec.Command(args[0], args[1:]...)
+}
+
+// batchCommand повторяет каждый аргумент с плейсхолдером для всех путей.
+func (t *commandTemplate) batchCommand(paths []string) *exec.Cmd {
+	var args []string
+	for _, arg := range t.args {
+		if hasPlaceholder(arg) {
+			for _, path := range paths {
+				args = append(args, expandPlaceholders(arg, path))
+			}
+			continue
+		}
+		args = append(args, arg)
+	}
+	return exec.Command(args[0], args[1:]...)
+}
+
+func expandPlaceholders(arg, path string) string {
+	base := filepath.Base(path)
+	r := strings.NewReplacer(
+		"{/.}", strings.TrimSuffix(base, filepath.Ext(base)),
+		"{//}", filepath.Dir(path),
+		"{/}", base,
+		"{.}", strings.TrimSuffix(path, filepath.Ext(path)),
+		"{}", path,
+	)
+	return r.Replace(arg)
+}
+
+// splitCommand разбивает строку на аргументы с учётом кавычек и экранирования.
+func splitCommand(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+
+	for i, runes := 0, []rune(s); i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if r == '\\' && quote == '"' && i+1 < len(runes) {
+				i++
+				cur.WriteRune(runes[i])
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == '\\' && i+1 < len(runes):
+			i++
+			cur.WriteRune(runes[i])
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in command %q", s)
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+type execFailure struct {
+	target string
+	err    error
+}
+
+// execRunner запускает команды с ограниченным параллелизмом. Вывод каждой
+// команды буферизуется и печатается целиком, чтобы не перемешиваться.
+type execRunner struct {
+	semaphore chan struct{}
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	total     int
+	failures  []execFailure
+}
+
+func newExecRunner(jobs int) *execRunner {
+	if jobs < 1 {
+		jobs = 1
+	}
+	return &execRunner{semaphore: make(chan struct{}, jobs)}
+}
+
+func (r *execRunner) run(target string, c *exec.Cmd) {
+	r.wg.Add(1)
+	r.semaphore <- struct{}{}
+	go func() {
+		defer r.wg.Done()
+		defer func() { <-r.semaphore }()
+
+		var stdout, stderr bytes.Buffer
+		c.Stdin = nil
+		c.Stdout = &stdout
+		c.Stderr = &stderr
+		err := c.Run()
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		os.Stdout.Write(stdout.Bytes())
+		os.Stderr.Write(stderr.Bytes())
+		r.total++
+		if err != nil {
+			r.failures = append(r.failures, execFailure{target: target, err: err})
+		}
+	}()
+}
+
+func (r *execRunner) wait() {
+	r.wg.Wait()
+}
+
+// printSummary печатает список неудачных запусков и возвращает ошибку, если они были.
+func (r *execRunner) printSummary() error {
+	if len(r.failures) == 0 {
+		return nil
+	}
+
+	sort.Slice(r.failures, func(i, j int) bool {
+		return r.failures[i].target < r.failures[j].target
+	})
+
+	fmt.Fprintf(os.Stderr, "\n%s\n", color.New(color.FgHiRed, color.Bold).Sprintf(
+		"%d of %d commands failed:", len(r.failures), r.total))
+	for _, failure := range r.failures {
+		var exitErr *exec.ExitError
+		if errors.As(failure.err, &exitErr) {
+			fmt.Fprintf(os.Stderr, "▸ %s %s\n", failure.target,
+				color.HiBlackString("(exit code %d)", exitErr.ExitCode()))
+		} else {
+			fmt.Fprintf(os.Stderr, "▸ %s %s\n", failure.target, color.HiBlackString("(%v)", failure.err))
+		}
+	}
+	return fmt.Errorf("%d of %d commands failed", len(r.failures), r.total)
+}
+
+// runBatches запускает команду для путей, разбитых на пачки по суммарной длине.
+// Чтобы пачки шли по очереди, runner создаётся с одним слотом.
+func (r *execRunner) runBatches(t *commandTemplate, paths []string) {
+	batches := splitBatches(paths)
+	if len(batches) > 1 {
+		// Команда вроде tar czf out.tgz {} при повторном вызове перезапишет
+		// результат предыдущей пачки.
+		fmt.Fprintf(os.Stderr, "%s\n", color.New(color.FgHiYellow, color.Bold).Sprintf(
+			"Warning: %d paths do not fit into one command line, the command runs %d times",
+			len(paths), len(batches)))
+	}
+	for _, batch := range batches {
+		r.run(batchTarget(batch), t.batchCommand(batch))
+	}
+}
+
+// splitBatches делит пути на пачки, суммарная длина которых не больше maxBatchArgsLen.
+func splitBatches(paths []string) [][]string {
+	var batches [][]string
+	start, size := 0, 0
+	for i, path := range paths {
+		if size+len(path) > maxBatchArgsLen && i > start {
+			batches = append(batches, paths[start:i])
+			start, size = i, 0
+		}
+		size += len(path) + 1
+	}
+	if start < len(paths) {
+		batches = append(batches, paths[start:])
+	}
+	return batches
+}
+
+func batchTarget(paths []string) string {
+	if len(paths) == 1 {
+		return paths[0]
+	}
+	return fmt.Sprintf("batch of %d paths (%s ... %s)", len(paths), paths[0], paths[len(paths)-1])
+}
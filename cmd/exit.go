@@ -0,0 +1,25 @@
+package cmd
+
+import "errors"
+
+// Коды завершения процесса.
+const (
+	ExitOK       = 0
+	ExitFindings = 1
+	ExitError    = 2
+)
+
+// ErrFindings возвращается, когда команда отработала успешно, но сработало
+// условие одного из флагов --fail-*.
+var ErrFindings = errors.New("policy check failed")
+
+// ExitCode сопоставляет ошибку, возвращённую командой, с кодом завершения.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrFindings):
+		return ExitFindings
+	}
+	return ExitError
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/SHCDevelops/file-manager/pkg/filemanager"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var FindClonesCmd = &cobra.Command{
+	Use:   "find-clones [directory]",
+	Short: "Find copy-pasted blocks of code within and across files",
+	Long: `This command finds fragments of code that are repeated in one or several files.
+
+Lines are compared after removing comments, blank lines and whitespace, so a block that
+was copied and reindented is still found. Lines without letters or digits (closing
+brackets) are ignored. A fragment must be at least --min-lines such lines long.
+
+A shorter clone may lie inside a longer one when part of the fragment is repeated
+elsewhere too; the total counts every duplicated line once. Only the first 64 occurrences
+of a fragment are compared, and such clones are marked as not listing all copies.
+
+Generated and vendored files are skipped unless --include-generated or --include-vendored
+is set.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		directory := args[0]
+
+		format, _ := cmd.Flags().GetString("format")
+		if format != "text" && format != "json" {
+			return fmt.Errorf("invalid --format value %q, expected text or json", format)
+		}
+		minLines, _ := cmd.Flags().GetInt("min-lines")
+		if minLines < 2 {
+			return fmt.Errorf("--min-lines must be at least 2")
+		}
+		top, _ := cmd.Flags().GetInt("top")
+
+		opts, stopProgress, err := scanOptions(cmd)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, codeOptions(cmd)...)
+
+		clones, err := filemanager.FindClones(cmd.Context(), directory, minLines, opts...)
+		stopProgress()
+
+		warnings, err := scanWarnings(err)
+		defer printWarnings(warnings)
+		if err != nil && !partial(err) {
+			return err
+		}
+
+		shown := clones
+		if top > 0 && len(shown) > top {
+			shown = shown[:top]
+		}
+		if format == "json" {
+			if writeErr := writeClonesJSON(os.Stdout, shown); writeErr != nil {
+				return writeErr
+			}
+		} else {
+			printClones(clones, shown)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if failOnClones, _ := cmd.Flags().GetBool("fail-on-clones"); failOnClones && len(clones) > 0 {
+			return fmt.Errorf("%w: %d code clones found", ErrFindings, len(clones))
+		}
+		return nil
+	},
+}
+
+// printClones печатает shown и итог по всем клонам.
+func printClones(clones, shown []filemanager.Clone) {
+	if len(clones) == 0 {
+		color.Green("No code clones found. 🎉")
+		return
+	}
+
+	groupHeader := color.New(color.FgHiRed, color.Bold).SprintFunc()
+	fileColor := color.New(color.FgHiYellow).SprintFunc()
+	header := color.New(color.FgHiMagenta, color.Bold).SprintFunc()
+
+	for i, clone := range shown {
+		fmt.Printf("\n%s %d: %d lines, %d copies", groupHeader("Clone"), i+1, clone.Lines, len(clone.Locations))
+		if clone.Truncated {
+			fmt.Print(color.HiBlackString(" (repeated too often, not all copies are listed)"))
+		}
+		fmt.Println()
+		for _, loc := range clone.Locations {
+			fmt.Printf("▸ %s\n", fileColor(fmt.Sprintf("%s:%d-%d", loc.Path, loc.StartLine, loc.EndLine)))
+		}
+	}
+
+	fmt.Printf("\n%s %d clones, %d duplicated lines of code\n", header("Total:"), len(clones), filemanager.TotalDuplicatedLines(clones))
+	if len(shown) < len(clones) {
+		fmt.Printf("(showing the largest %d, use --top 0 to see all)\n", len(shown))
+	}
+}
+
+type cloneLocationJSON struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+}
+
+type cloneJSON struct {
+	Lines     int                 `json:"lines"`
+	Locations []cloneLocationJSON `json:"locations"`
+	Truncated bool                `json:"truncated,omitempty"`
+}
+
+func writeClonesJSON(w io.Writer, clones []filemanager.Clone) error {
+	result := make([]cloneJSON, len(clones))
+	for i, clone := range clones {
+		result[i] = cloneJSON{Lines: clone.Lines, Locations: make([]cloneLocationJSON, len(clone.Locations)), Truncated: clone.Truncated}
+		for j, loc := range clone.Locations {
+			result[i].Locations[j] = cloneLocationJSON{Path: loc.Path, StartLine: loc.StartLine, EndLine: loc.EndLine}
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+func init() {
+	FindClonesCmd.Flags().Int("min-lines", filemanager.DefaultCloneLines, "Minimum size of a clone in lines of code")
+	FindClonesCmd.Flags().IntP("top", "t", 20, "Number of largest clones to show (0 for all)")
+	FindClonesCmd.Flags().String("format", "text", "Output format: text or json")
+	FindClonesCmd.Flags().Bool("fail-on-clones", false, "Exit with code 1 if clones are found")
+	FindClonesCmd.Flags().StringP("ignore", "i", "", "Comma-separated list of directories or patterns to ignore")
+	FindClonesCmd.Flags().StringP("ignore-language", "l", "", "Comma-separated list of languages to ignore")
+	FindClonesCmd.Flags().Bool("include-generated", false, includeGeneratedFlagUsage)
+	FindClonesCmd.Flags().Bool("include-vendored", false, includeVendoredFlagUsage)
+	FindClonesCmd.Flags().String("progress", "auto", progressFlagUsage)
+	FindClonesCmd.Flags().Bool("archives", false, archivesFlagUsage)
+	FindClonesCmd.Flags().Bool("strict", false, "Stop on the first unreadable file instead of skipping it")
+}
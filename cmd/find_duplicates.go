@@ -2,29 +2,35 @@ package cmd
 
 import (
 	"fmt"
-	"github.com/SHCDevelops/file-manager/internal/filesystem"
+	"github.com/SHCDevelops/file-manager/pkg/filemanager"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
-	"strings"
 )
 
 var FindDuplicatesCmd = &cobra.Command{
 	Use:   "find-duplicates [directory]",
 	Short: "Find duplicate files in the specified directory",
 	Long: `This command scans the specified directory and finds duplicate files based on their content.
-It uses file hashes to identify duplicates.`,
+It uses file hashes to identify duplicates. Only files whose size matches another file
+are hashed, in parallel on all CPU cores.`,
 	Args: cobra.MinimumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		directory := args[0]
 
-		ignorePattern, _ := cmd.Flags().GetString("ignore")
-		ignoreList := strings.Split(ignorePattern, ",")
+		opts, stopProgress, err := scanOptions(cmd)
+		if err != nil {
+			return err
+		}
+		hashAlgorithm, _ := cmd.Flags().GetString("hash")
+		opts = append(opts, filemanager.WithHashAlgorithm(filemanager.HashAlgorithm(hashAlgorithm)))
 
-		duplicates, err := filesystem.FindDuplicates(directory, ignoreList)
+		duplicates, err := filemanager.FindDuplicates(cmd.Context(), directory, opts...)
+		stopProgress()
 
-		if err != nil {
-			color.Red("Error: %v\n", err)
-			return
+		warnings, err := scanWarnings(err)
+		defer printWarnings(warnings)
+		if err != nil && !partial(err) {
+			return err
 		}
 
 		if len(duplicates) == 0 {
@@ -41,9 +47,23 @@ It uses file hashes to identify duplicates.`,
 				}
 			}
 		}
+
+		if err != nil {
+			return err
+		}
+
+		if failOnDuplicates, _ := cmd.Flags().GetBool("fail-on-duplicates"); failOnDuplicates && len(duplicates) > 0 {
+			return fmt.Errorf("%w: %d groups of duplicate files found", ErrFindings, len(duplicates))
+		}
+		return nil
 	},
 }
 
 func init() {
 	FindDuplicatesCmd.Flags().StringP("ignore", "i", "", "Comma-separated list of directories or patterns to ignore (e.g., temp,.git)")
+	FindDuplicatesCmd.Flags().String("hash", "md5", "Hash algorithm used to compare files: md5, sha1, sha256 or sha512")
+	FindDuplicatesCmd.Flags().Bool("fail-on-duplicates", false, "Exit with code 1 if duplicates are found")
+	FindDuplicatesCmd.Flags().String("progress", "auto", progressFlagUsage)
+	FindDuplicatesCmd.Flags().Bool("archives", false, archivesFlagUsage)
+	FindDuplicatesCmd.Flags().Bool("strict", false, "Stop on the first unreadable file instead of skipping it")
 }
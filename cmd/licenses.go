@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/SHCDevelops/file-manager/pkg/filemanager"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var LicensesCmd = &cobra.Command{
+	Use:   "licenses [directory]",
+	Short: "Inventory licenses of source files and LICENSE files",
+	Long: `This command builds a license inventory of a directory:
+- LICENSE, LICENCE, COPYING and UNLICENSE files are identified by their text
+- source files are identified by the comment block before the first line of code,
+  using an SPDX-License-Identifier tag or a recognised license text
+
+Source files without a license header are listed, as are files whose header names a
+license different from the nearest LICENSE file in their directory or above.
+
+Recognised texts: Apache-2.0, MIT, BSD-2-Clause, BSD-3-Clause, ISC, MPL-2.0, EPL-2.0,
+GPL-2.0, GPL-3.0, LGPL-2.1, LGPL-3.0, AGPL-3.0, BSL-1.0, Unlicense and CC0-1.0.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		directory := args[0]
+
+		format, _ := cmd.Flags().GetString("format")
+		if format != "text" && format != "json" {
+			return fmt.Errorf("invalid --format value %q, expected text or json", format)
+		}
+
+		opts, stopProgress, err := scanOptions(cmd)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, codeOptions(cmd)...)
+
+		report, err := filemanager.FindLicenses(cmd.Context(), directory, opts...)
+		stopProgress()
+
+		warnings, err := scanWarnings(err)
+		defer printWarnings(warnings)
+		if err != nil && !partial(err) {
+			return err
+		}
+
+		if format == "json" {
+			if writeErr := writeLicensesJSON(os.Stdout, report); writeErr != nil {
+				return writeErr
+			}
+		} else {
+			printLicenses(report)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var findings []string
+		if failOnMissing, _ := cmd.Flags().GetBool("fail-on-missing"); failOnMissing {
+			if missing := len(report.Missing()); missing > 0 {
+				findings = append(findings, fmt.Sprintf("%d files without a license header", missing))
+			}
+		}
+		if failOnConflicts, _ := cmd.Flags().GetBool("fail-on-conflicts"); failOnConflicts {
+			if conflicts := len(report.Conflicts()); conflicts > 0 {
+				findings = append(findings, fmt.Sprintf("%d files with conflicting licenses", conflicts))
+			}
+		}
+		if len(findings) > 0 {
+			return fmt.Errorf("%w: %s", ErrFindings, strings.Join(findings, ", "))
+		}
+		return nil
+	},
+}
+
+func printLicenses(report *filemanager.LicenseReport) {
+	header := color.New(color.FgHiMagenta, color.Bold).SprintFunc()
+	fileColor := color.New(color.FgHiCyan).SprintFunc()
+	licenseColor := color.New(color.FgHiGreen).SprintFunc()
+	warnColor := color.New(color.FgHiYellow).SprintFunc()
+	conflictColor := color.New(color.FgHiRed, color.Bold).SprintFunc()
+
+	fmt.Printf("\n%s\n", header("License files:"))
+	if len(report.LicenseFiles) == 0 {
+		fmt.Println(warnColor("none found"))
+	}
+	for _, lf := range report.LicenseFiles {
+		license := lf.License
+		if license == "" {
+			license = warnColor("unrecognised")
+		} else {
+			license = licenseColor(license)
+		}
+		fmt.Printf("▸ %s  %s\n", fileColor(lf.Path), license)
+	}
+
+	summary := report.Summary()
+	licenses := make([]string, 0, len(summary))
+	for license := range summary {
+		licenses = append(licenses, license)
+	}
+	sort.Slice(licenses, func(i, j int) bool {
+		if summary[licenses[i]] != summary[licenses[j]] {
+			return summary[licenses[i]] > summary[licenses[j]]
+		}
+		return licenses[i] < licenses[j]
+	})
+	fmt.Printf("\n%s\n", header("Source files by license header:"))
+	for _, license := range licenses {
+		name := licenseColor(license)
+		if license == "" {
+			name = warnColor("no header")
+		}
+		fmt.Printf("%s: %d (%.1f%%)\n", name, summary[license], percent(summary[license], len(report.Files)))
+	}
+
+	if missing := report.Missing(); len(missing) > 0 {
+		fmt.Printf("\n%s\n", header(fmt.Sprintf("Files without a license header (%d):", len(missing))))
+		for _, f := range missing {
+			fmt.Printf("▸ %s\n", fileColor(f.Path))
+		}
+	}
+
+	if conflicts := report.Conflicts(); len(conflicts) > 0 {
+		fmt.Printf("\n%s\n", header(fmt.Sprintf("Conflicting licenses (%d):", len(conflicts))))
+		for _, f := range conflicts {
+			fmt.Printf("▸ %s  %s, LICENSE: %s\n", fileColor(f.Path), conflictColor(f.License), f.Governing)
+		}
+	}
+}
+
+type fileLicenseJSON struct {
+	Path      string `json:"path"`
+	License   string `json:"license"`
+	Governing string `json:"governing,omitempty"`
+	Conflict  bool   `json:"conflict,omitempty"`
+}
+
+type licenseReportJSON struct {
+	LicenseFiles []fileLicenseJSON `json:"license_files"`
+	Files        []fileLicenseJSON `json:"files"`
+}
+
+func writeLicensesJSON(w io.Writer, report *filemanager.LicenseReport) error {
+	convert := func(files []filemanager.FileLicense) []fileLicenseJSON {
+		result := make([]fileLicenseJSON, len(files))
+		for i, f := range files {
+			result[i] = fileLicenseJSON{Path: f.Path, License: f.License, Governing: f.Governing, Conflict: f.Conflict}
+		}
+		return result
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(licenseReportJSON{
+		LicenseFiles: convert(report.LicenseFiles),
+		Files:        convert(report.Files),
+	})
+}
+
+func init() {
+	LicensesCmd.Flags().String("format", "text", "Output format: text or json")
+	LicensesCmd.Flags().Bool("fail-on-missing", false, "Exit with code 1 if any source file has no license header")
+	LicensesCmd.Flags().Bool("fail-on-conflicts", false, "Exit with code 1 if a file header conflicts with its LICENSE file")
+	LicensesCmd.Flags().StringP("ignore", "i", "", "Comma-separated list of directories or patterns to ignore")
+	LicensesCmd.Flags().StringP("ignore-language", "l", "", "Comma-separated list of languages to ignore")
+	LicensesCmd.Flags().Bool("include-generated", false, includeGeneratedFlagUsage)
+	LicensesCmd.Flags().Bool("include-vendored", false, includeVendoredFlagUsage)
+	LicensesCmd.Flags().String("progress", "auto", progressFlagUsage)
+	LicensesCmd.Flags().Bool("archives", false, archivesFlagUsage)
+	LicensesCmd.Flags().Bool("strict", false, "Stop on the first unreadable file instead of skipping it")
+}
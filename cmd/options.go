@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/SHCDevelops/file-manager/internal/progress"
+	"github.com/SHCDevelops/file-manager/pkg/filemanager"
+	"github.com/spf13/cobra"
+)
+
+const (
+	progressFlagUsage = "Show scan progress on stderr: auto (a status line on a terminal, a log line every 5s otherwise), always (a status line even if stderr is not a terminal) or never"
+	archivesFlagUsage = "Also scan inside .zip, .tar, .tar.gz and .tgz files (paths look like bundle.zip!/src/main.go); tar archives are read into memory, up to 256 MiB each"
+
+	includeGeneratedFlagUsage = "Treat generated and minified files and JSON/YAML lockfiles as source code instead of excluding them"
+	includeVendoredFlagUsage  = "Treat third-party code (vendor/, node_modules/, third_party/) as source code instead of excluding it"
+)
+
+// codeOptions собирает опции подсчёта строк из флагов --ignore-language,
+// --include-generated и --include-vendored.
+func codeOptions(cmd *cobra.Command) []filemanager.Option {
+	ignoreLanguagePattern, _ := cmd.Flags().GetString("ignore-language")
+	includeGenerated, _ := cmd.Flags().GetBool("include-generated")
+	includeVendored, _ := cmd.Flags().GetBool("include-vendored")
+	return []filemanager.Option{
+		filemanager.WithIgnoreLanguages(strings.Split(ignoreLanguagePattern, ",")...),
+		filemanager.WithIncludeGenerated(includeGenerated),
+		filemanager.WithIncludeVendored(includeVendored),
+	}
+}
+
+// scanOptions собирает общие опции сканирования из флагов --ignore, --strict,
+// --archives и --progress. Возвращённую функцию нужно вызвать до печати результатов:
+// она убирает строку прогресса.
+func scanOptions(cmd *cobra.Command) ([]filemanager.Option, func(), error) {
+	ignorePattern, _ := cmd.Flags().GetString("ignore")
+	strict, _ := cmd.Flags().GetBool("strict")
+
+	opts := []filemanager.Option{
+		filemanager.WithIgnore(strings.Split(ignorePattern, ",")...),
+		filemanager.WithStrict(strict),
+	}
+
+	if cmd.Flags().Lookup("archives") != nil {
+		archives, _ := cmd.Flags().GetBool("archives")
+		opts = append(opts, filemanager.WithArchives(archives))
+	}
+
+	if cmd.Flags().Lookup("progress") == nil {
+		return opts, func() {}, nil
+	}
+
+	// При auto строка состояния рисуется только на терминале, иначе раз
+	// в 5 секунд печатается строка лога.
+	var renderer *progress.Renderer
+	mode, _ := cmd.Flags().GetString("progress")
+	switch mode {
+	case "never":
+		return opts, func() {}, nil
+	case "auto":
+		renderer = progress.NewRenderer(os.Stderr)
+	case "always":
+		renderer = progress.NewStatusLineRenderer(os.Stderr)
+	default:
+		return nil, nil, fmt.Errorf("invalid --progress value %q, expected auto, always or never", mode)
+	}
+
+	opts = append(opts, filemanager.WithProgress(renderer.Interval(), func(p filemanager.Progress) {
+		renderer.Update(progress.Snapshot(p))
+	}))
+	return opts, renderer.Close, nil
+}
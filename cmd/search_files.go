@@ -2,46 +2,280 @@ package cmd
 
 import (
 	"fmt"
-	"github.com/SHCDevelops/file-manager/internal/filesystem"
+	"github.com/SHCDevelops/file-manager/pkg/filemanager"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
-	"strings"
+	"path/filepath"
+	"runtime"
 )
 
 var SearchCmd = &cobra.Command{
-	Use:   "search [pattern] [directory]",
+	Use:   "search [pattern...] [directory]",
 	Short: "Search for files matching a pattern in the specified directory",
-	Long: `This command searches for files that match a given pattern in the specified directory.
-You can ignore specific directories using the --ignore flag.`,
+	Long: `This command searches for files and directories that match a given pattern in the specified directory.
+Several patterns can be given, a file matches if any of them matches.
+By default patterns are globs matched against the file name; use --regex or --fuzzy
+to change the syntax and --full-path to match against the path relative to the directory.
+You can ignore specific directories using the --ignore flag.
+Symbolic links are reported as links unless --follow is given.
+Results are printed as they are found, in path order; use --sort to order them
+by size or modification time and --max-results to stop early (with --sort or
+--fuzzy the whole directory is scanned and the first N sorted results are printed).
+
+Use --exec or --exec-batch to run a command on the results instead of piping them
+into xargs, or --print0 for NUL-separated output. If the paths given to --exec-batch
+do not fit into one command line, they are split into several invocations that run
+one after another and a warning is printed, so use commands that are safe to repeat;
+--jobs applies to --exec only.
+
+Files can also be filtered by metadata (--type, --size, --mtime, --perm, --owner,
+--empty, --newer). All given filters must match; use --where for OR/NOT expressions,
+e.g. --where "type=f and (size=+10M or not mtime=-7d)".`,
 	Args: cobra.MinimumNArgs(2),
-	Run: func(cmd *cobra.Command, args []string) {
-		pattern := args[0]
-		directory := args[1]
+	RunE: func(cmd *cobra.Command, args []string) error {
+		patterns := args[:len(args)-1]
+		directory := args[len(args)-1]
 
-		ignorePattern, _ := cmd.Flags().GetString("ignore")
-		ignoreList := strings.Split(ignorePattern, ",")
+		useRegex, _ := cmd.Flags().GetBool("regex")
+		useFuzzy, _ := cmd.Flags().GetBool("fuzzy")
+		fullPath, _ := cmd.Flags().GetBool("full-path")
+		follow, _ := cmd.Flags().GetBool("follow")
+		maxResults, _ := cmd.Flags().GetInt("max-results")
+		sortBy, _ := cmd.Flags().GetString("sort")
+		if useFuzzy && sortBy == "" {
+			sortBy = "score"
+		}
+		if sortBy != "" {
+			// Ключ проверяется до обхода, а не после полного сканирования.
+			if err := filemanager.SortSearchResults(nil, sortBy); err != nil {
+				return err
+			}
+		}
+		ignoreCase, _ := cmd.Flags().GetBool("iname")
 
-		matchedFiles, err := filesystem.SearchFiles(directory, pattern, ignoreList)
+		matchOpts := filemanager.MatchOptions{IgnoreCase: ignoreCase, FullPath: fullPath}
+		switch {
+		case useRegex:
+			matchOpts.Mode = filemanager.MatchRegex
+		case useFuzzy:
+			matchOpts.Mode = filemanager.MatchFuzzy
+		}
 
+		matcher, err := filemanager.NewMatcher(patterns, matchOpts)
 		if err != nil {
-			color.Red("Error: %v\n", err)
-			return
+			return err
 		}
 
-		if len(matchedFiles) == 0 {
-			color.Yellow("No files found.")
-		} else {
-			header := color.New(color.FgHiGreen, color.Bold).SprintFunc()
-			fileColor := color.New(color.FgHiWhite).SprintFunc()
+		predicate, err := searchPredicate(cmd)
+		if err != nil {
+			return err
+		}
 
-			fmt.Printf("\n%s\n", header("Matching files:"))
-			for _, file := range matchedFiles {
-				fmt.Printf("▸ %s\n", fileColor(file))
+		opts, _, err := scanOptions(cmd)
+		if err != nil {
+			return err
+		}
+		opts = append(opts,
+			filemanager.WithPredicate(predicate),
+			filemanager.WithFollow(follow),
+		)
+
+		emit, finish, err := searchOutput(cmd)
+		if err != nil {
+			return err
+		}
+
+		var warnings filemanager.ScanErrors
+		if sortBy == "" {
+			opts = append(opts, filemanager.WithMaxResults(maxResults))
+			warnings, err = scanWarnings(filemanager.StreamSearch(cmd.Context(), directory, matcher, emit, opts...))
+		} else {
+			// Первые N после сортировки: обход нельзя остановить раньше.
+			var matchedFiles []filemanager.SearchResult
+			matchedFiles, err = filemanager.Search(cmd.Context(), directory, matcher, opts...)
+			warnings, err = scanWarnings(err)
+			if err == nil || partial(err) {
+				if sortErr := filemanager.SortSearchResults(matchedFiles, sortBy); sortErr != nil {
+					return sortErr
+				}
+				if maxResults > 0 && len(matchedFiles) > maxResults {
+					matchedFiles = matchedFiles[:maxResults]
+				}
+				for _, result := range matchedFiles {
+					if emitErr := emit(result); emitErr != nil {
+						err = emitErr
+						break
+					}
+				}
 			}
 		}
+
+		execErr := finish()
+		printWarnings(warnings)
+
+		if err != nil {
+			return err
+		}
+		return execErr
 	},
 }
 
 func init() {
 	SearchCmd.Flags().StringP("ignore", "i", "", "Comma-separated list of directories or patterns to ignore (e.g., temp,.git)")
+	SearchCmd.Flags().Bool("regex", false, "Treat patterns as regular expressions")
+	SearchCmd.Flags().BoolP("fuzzy", "z", false, "Fuzzy match patterns and rank results by score (like fzf)")
+	SearchCmd.Flags().BoolP("full-path", "p", false, "Match patterns against the path relative to the directory instead of the file name")
+	SearchCmd.Flags().Bool("iname", false, "Case-insensitive matching")
+	SearchCmd.Flags().BoolP("follow", "L", false, "Follow symbolic links (link cycles are detected and not descended into)")
+	SearchCmd.Flags().StringP("type", "t", "", "Entry type: f (file), d (directory), l (symlink); comma-separated")
+	SearchCmd.Flags().StringArray("size", nil, "File size: +N (more than), -N (less than) or N, with k/M/G suffix (e.g., +10M)")
+	SearchCmd.Flags().StringArray("mtime", nil, "Modification age: +N (older than), -N (newer than) or N, with s/m/h/d/w suffix (e.g., -7d)")
+	SearchCmd.Flags().String("perm", "", "Permission bits in octal: MODE (exact), -MODE (all bits set), /MODE (any bit set)")
+	SearchCmd.Flags().String("owner", "", "Owner user name or uid")
+	SearchCmd.Flags().Bool("empty", false, "Only empty files and directories")
+	SearchCmd.Flags().String("newer", "", "Only files modified after the given file")
+	SearchCmd.Flags().String("where", "", "Predicate expression with and/or/not and parentheses (e.g., \"size=+1M or empty\")")
+	SearchCmd.Flags().String("sort", "", "Print results sorted by path, size, mtime or score instead of streaming them as found")
+	SearchCmd.Flags().IntP("max-results", "n", 0, "Stop searching after N results; with --sort, print the top N (0 means no limit)")
+	SearchCmd.Flags().Bool("archives", false, archivesFlagUsage)
+	SearchCmd.Flags().Bool("strict", false, "Stop on the first unreadable entry instead of skipping it")
+	SearchCmd.Flags().StringP("exec", "x", "", "Execute a command for each result, e.g. 'gzip {}' ({}, {/}, {//}, {.}, {/.} are substituted)")
+	SearchCmd.Flags().StringP("exec-batch", "X", "", "Execute a command once with all results, e.g. 'ls -l {}'")
+	SearchCmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "Number of commands to run in parallel with --exec")
+	SearchCmd.Flags().BoolP("print0", "0", false, "Print results separated by NUL characters (for xargs -0)")
+	SearchCmd.MarkFlagsMutuallyExclusive("regex", "fuzzy")
+	SearchCmd.MarkFlagsMutuallyExclusive("exec", "exec-batch", "print0")
+}
+
+// searchOutput выбирает, что делать с найденными записями: печатать их,
+// выводить через NUL или запускать для них команды. finish вызывается после поиска
+// и возвращает ошибку, если какие-то из запущенных команд завершились неудачно.
+func searchOutput(cmd *cobra.Command) (emit func(filemanager.SearchResult) error, finish func() error, err error) {
+	execCommand, _ := cmd.Flags().GetString("exec")
+	execBatch, _ := cmd.Flags().GetString("exec-batch")
+	jobs, _ := cmd.Flags().GetInt("jobs")
+	print0, _ := cmd.Flags().GetBool("print0")
+
+	switch {
+	case execCommand != "":
+		tmpl, err := parseCommandTemplate(execCommand)
+		if err != nil {
+			return nil, nil, err
+		}
+		runner := newExecRunner(jobs)
+		emit = func(result filemanager.SearchResult) error {
+			runner.run(result.Path, tmpl.command(result.Path))
+			return nil
+		}
+		finish = func() error {
+			runner.wait()
+			return runner.printSummary()
+		}
+		return emit, finish, nil
+
+	case execBatch != "":
+		tmpl, err := parseCommandTemplate(execBatch)
+		if err != nil {
+			return nil, nil, err
+		}
+		var paths []string
+		emit = func(result filemanager.SearchResult) error {
+			paths = append(paths, result.Path)
+			return nil
+		}
+		finish = func() error {
+			if len(paths) == 0 {
+				return nil
+			}
+			// Пачки одной команды выполняются по очереди, как в xargs: параллельные
+			// вызовы (например, tar czf out.tgz {}) писали бы в один и тот же файл.
+			runner := newExecRunner(1)
+			runner.runBatches(tmpl, paths)
+			runner.wait()
+			return runner.printSummary()
+		}
+		return emit, finish, nil
+
+	case print0:
+		emit = func(result filemanager.SearchResult) error {
+			_, err := fmt.Printf("%s\x00", result.Path)
+			return err
+		}
+		return emit, func() error { return nil }, nil
+	}
+
+	header := color.New(color.FgHiGreen, color.Bold).SprintFunc()
+	found := 0
+	emit = func(result filemanager.SearchResult) error {
+		if found == 0 {
+			fmt.Printf("\n%s\n", header("Matching files:"))
+		}
+		found++
+		printSearchResult(result)
+		return nil
+	}
+	finish = func() error {
+		if found == 0 {
+			color.Yellow("No files found.")
+		}
+		return nil
+	}
+	return emit, finish, nil
+}
+
+func printSearchResult(result filemanager.SearchResult) {
+	switch result.Type {
+	case filemanager.EntryDir:
+		fmt.Printf("▸ %s\n", color.HiBlueString(result.Path+string(filepath.Separator)))
+	case filemanager.EntrySymlink:
+		fmt.Printf("▸ %s %s\n", color.HiCyanString(result.Path), color.HiBlackString("(symlink)"))
+	default:
+		fmt.Printf("▸ %s\n", color.HiWhiteString(result.Path))
+	}
+}
+
+// searchPredicate собирает предикаты из флагов; все они объединяются через И.
+func searchPredicate(cmd *cobra.Command) (filemanager.Predicate, error) {
+	var preds []filemanager.Predicate
+	add := func(name, value string) error {
+		pred, err := filemanager.NewPredicate(name, value)
+		if err != nil {
+			return err
+		}
+		preds = append(preds, pred)
+		return nil
+	}
+
+	for _, name := range []string{"type", "perm", "owner", "newer"} {
+		if value, _ := cmd.Flags().GetString(name); value != "" {
+			if err := add(name, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, name := range []string{"size", "mtime"} {
+		values, _ := cmd.Flags().GetStringArray(name)
+		for _, value := range values {
+			if err := add(name, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if empty, _ := cmd.Flags().GetBool("empty"); empty {
+		if err := add("empty", ""); err != nil {
+			return nil, err
+		}
+	}
+	if expr, _ := cmd.Flags().GetString("where"); expr != "" {
+		pred, err := filemanager.ParsePredicate(expr)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, pred)
+	}
+
+	if len(preds) == 0 {
+		return nil, nil
+	}
+	return filemanager.And(preds...), nil
 }
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/SHCDevelops/file-manager/pkg/filemanager"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var SnapshotCmd = &cobra.Command{
+	Use:   "snapshot [directory]",
+	Short: "Save the state of a directory tree to a file",
+	Long: `This command records paths, sizes, modification times, permissions and optionally
+content hashes of all files in the directory. Compare snapshots with the diff command.
+Entries that cannot be read are skipped and recorded in the snapshot: diff does not
+report them, nor anything inside them, as added, removed or modified.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		directory := args[0]
+		output, _ := cmd.Flags().GetString("output")
+
+		opts, stopProgress, err := scanOptions(cmd)
+		if err != nil {
+			return err
+		}
+		hashAlgorithm, _ := cmd.Flags().GetString("hash")
+		opts = append(opts, filemanager.WithHashAlgorithm(filemanager.HashAlgorithm(hashAlgorithm)))
+
+		snapshot, err := filemanager.TakeSnapshot(cmd.Context(), directory, opts...)
+		stopProgress()
+
+		warnings, err := scanWarnings(err)
+		defer printWarnings(warnings)
+		if err != nil {
+			// Неполный снимок при сравнении выглядел бы как удаление файлов.
+			return err
+		}
+
+		if err := writeSnapshotFile(output, snapshot); err != nil {
+			return err
+		}
+		color.Green("Snapshot of %d files saved to %s", len(snapshot.Entries), output)
+		if len(snapshot.Skipped) > 0 {
+			fmt.Fprintf(os.Stderr, "%s\n", color.HiBlackString(
+				"%d unreadable paths are recorded in the snapshot and will not be compared by diff", len(snapshot.Skipped)))
+		}
+		return nil
+	},
+}
+
+func writeSnapshotFile(name string, snapshot *filemanager.Snapshot) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := filemanager.WriteSnapshot(file, snapshot); err != nil {
+		file.Close()
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	return file.Close()
+}
+
+func readSnapshotFile(name string) (*filemanager.Snapshot, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	snapshot, err := filemanager.ReadSnapshot(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return snapshot, nil
+}
+
+func init() {
+	SnapshotCmd.Flags().StringP("output", "o", "", "File to write the snapshot to")
+	SnapshotCmd.Flags().String("hash", "", "Also store content hashes: md5, sha1, sha256 or sha512 (slower, detects edits that keep size and mtime)")
+	SnapshotCmd.Flags().StringP("ignore", "i", "", "Comma-separated list of directories or patterns to ignore (e.g., temp,.git)")
+	SnapshotCmd.Flags().String("progress", "auto", progressFlagUsage)
+	SnapshotCmd.Flags().Bool("archives", false, archivesFlagUsage)
+	SnapshotCmd.Flags().Bool("strict", false, "Stop on the first unreadable entry instead of skipping it")
+	SnapshotCmd.MarkFlagRequired("output")
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/SHCDevelops/file-manager/pkg/filemanager"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var TodosCmd = &cobra.Command{
+	Use:   "todos [directory]",
+	Short: "List TODO, FIXME, HACK and XXX markers in code comments",
+	Long: `This command finds markers such as TODO, FIXME, HACK and XXX in the comments of files
+in supported languages and prints them with the path, line, author and text.
+
+An author can be given in parentheses right after the tag, e.g. FIXME(alice), and the rest
+of the line becomes the text. Markers in comments at the end of code lines are found too.
+Tags are case-sensitive; use --tags to search for your own.
+
+Generated and vendored files are skipped unless --include-generated or --include-vendored
+is set. Use --format json to feed the markers into an issue tracker.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		directory := args[0]
+
+		format, _ := cmd.Flags().GetString("format")
+		if format != "text" && format != "json" {
+			return fmt.Errorf("invalid --format value %q, expected text or json", format)
+		}
+		tagList, _ := cmd.Flags().GetString("tags")
+		var tags []string
+		for _, tag := range strings.Split(tagList, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		if len(tags) == 0 {
+			return fmt.Errorf("--tags must list at least one tag")
+		}
+
+		opts, stopProgress, err := scanOptions(cmd)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, codeOptions(cmd)...)
+
+		todos, err := filemanager.FindTodos(cmd.Context(), directory, tags, opts...)
+		stopProgress()
+
+		warnings, err := scanWarnings(err)
+		defer printWarnings(warnings)
+		if err != nil && !partial(err) {
+			return err
+		}
+
+		if format == "json" {
+			if writeErr := writeTodosJSON(os.Stdout, todos); writeErr != nil {
+				return writeErr
+			}
+		} else {
+			printTodos(todos)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if failOnTodos, _ := cmd.Flags().GetBool("fail-on-todos"); failOnTodos && len(todos) > 0 {
+			return fmt.Errorf("%w: %d todo markers found", ErrFindings, len(todos))
+		}
+		return nil
+	},
+}
+
+func printTodos(todos []filemanager.Todo) {
+	if len(todos) == 0 {
+		color.Green("No markers found. 🎉")
+		return
+	}
+
+	header := color.New(color.FgHiMagenta, color.Bold).SprintFunc()
+	pathColor := color.New(color.FgHiCyan).SprintFunc()
+	tagColor := color.New(color.FgHiYellow, color.Bold).SprintFunc()
+
+	counts := make(map[string]int)
+	for _, todo := range todos {
+		tag := todo.Tag
+		if todo.Author != "" {
+			tag += "(" + todo.Author + ")"
+		}
+		fmt.Printf("%s  %s  %s\n", pathColor(fmt.Sprintf("%s:%d", todo.Path, todo.Line)), tagColor(tag), todo.Text)
+		counts[todo.Tag]++
+	}
+
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+	parts := make([]string, len(tags))
+	for i, tag := range tags {
+		parts[i] = fmt.Sprintf("%s %d", tag, counts[tag])
+	}
+	fmt.Printf("\n%s %d (%s)\n", header("Markers found:"), len(todos), strings.Join(parts, ", "))
+}
+
+type todoJSON struct {
+	Path   string `json:"path"`
+	Line   int    `json:"line"`
+	Tag    string `json:"tag"`
+	Author string `json:"author,omitempty"`
+	Text   string `json:"text"`
+}
+
+func writeTodosJSON(w io.Writer, todos []filemanager.Todo) error {
+	result := make([]todoJSON, len(todos))
+	for i, todo := range todos {
+		result[i] = todoJSON{
+			Path:   todo.Path,
+			Line:   todo.Line,
+			Tag:    todo.Tag,
+			Author: todo.Author,
+			Text:   todo.Text,
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+func init() {
+	TodosCmd.Flags().String("tags", strings.Join(filemanager.DefaultTodoTags(), ","), "Comma-separated list of marker tags to search for")
+	TodosCmd.Flags().String("format", "text", "Output format: text or json")
+	TodosCmd.Flags().Bool("fail-on-todos", false, "Exit with code 1 if any markers are found")
+	TodosCmd.Flags().StringP("ignore", "i", "", "Comma-separated list of directories or patterns to ignore")
+	TodosCmd.Flags().StringP("ignore-language", "l", "", "Comma-separated list of languages to ignore")
+	TodosCmd.Flags().Bool("include-generated", false, includeGeneratedFlagUsage)
+	TodosCmd.Flags().Bool("include-vendored", false, includeVendoredFlagUsage)
+	TodosCmd.Flags().String("progress", "auto", progressFlagUsage)
+	TodosCmd.Flags().Bool("archives", false, archivesFlagUsage)
+	TodosCmd.Flags().Bool("strict", false, "Stop on the first unreadable file instead of skipping it")
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/SHCDevelops/file-manager/pkg/filemanager"
+	"github.com/fatih/color"
+)
+
+const maxPrintedWarnings = 10
+
+// errInterrupted и errTimedOut возвращаются командами, показавшими частичный результат.
+var (
+	errInterrupted = errors.New("scan interrupted, results are partial")
+	errTimedOut    = errors.New("scan timed out, results are partial")
+)
+
+// scanWarnings отделяет список пропущенных записей от ошибки, из-за которой
+// результата нет вовсе. Прерванное сканирование возвращает errInterrupted или
+// errTimedOut: частичный результат при этом всё равно стоит показать.
+func scanWarnings(err error) (filemanager.ScanErrors, error) {
+	var warnings filemanager.ScanErrors
+	errors.As(err, &warnings)
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return warnings, errInterrupted
+	case errors.Is(err, context.DeadlineExceeded):
+		return warnings, errTimedOut
+	case warnings != nil:
+		return warnings, nil
+	}
+	return nil, err
+}
+
+// partial сообщает, что ошибка означает прерванное сканирование с частичным результатом.
+func partial(err error) bool {
+	return errors.Is(err, errInterrupted) || errors.Is(err, errTimedOut)
+}
+
+func printWarnings(warnings filemanager.ScanErrors) {
+	if len(warnings) == 0 {
+		return
+	}
+
+	header := color.New(color.FgHiYellow, color.Bold).SprintFunc()
+	fmt.Fprintf(os.Stderr, "\n%s\n", header(fmt.Sprintf("Warnings: %d entries could not be scanned", len(warnings))))
+	for i, warning := range warnings {
+		if i == maxPrintedWarnings {
+			fmt.Fprintf(os.Stderr, "%s\n", color.HiBlackString("... and %d more (use --strict to stop on the first error)", len(warnings)-i))
+			break
+		}
+		printWarning(warning)
+	}
+}
+
+func printWarning(warning *filemanager.ScanError) {
+	fmt.Fprintf(os.Stderr, "▸ %s %s\n", warning.Path, color.HiBlackString("(%s: %v)", warning.Op, warning.Err))
+}
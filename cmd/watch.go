@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/SHCDevelops/file-manager/internal/progress"
+	"github.com/SHCDevelops/file-manager/pkg/filemanager"
+	"github.com/fatih/color"
+)
+
+const watchFlagUsage = "Keep watching the directory and print updated results as files change (Linux only)"
+
+// watchOptions дополняет опции для режима --watch: пропущенные записи печатаются
+// сразу, а не в конце, которого нет.
+func watchOptions(opts []filemanager.Option) []filemanager.Option {
+	return append(opts, filemanager.WithOnError(func(warning *filemanager.ScanError) {
+		printWarning(warning)
+	}))
+}
+
+// watchPrinter возвращает функцию, печатающую очередное обновление. На терминале
+// экран перед этим очищается, иначе обновления разделяются строкой со временем.
+func watchPrinter(stopProgress func(), print func()) func() {
+	stopProgress = sync.OnceFunc(stopProgress)
+	tty := progress.IsTerminal(os.Stdout)
+	return func() {
+		stopProgress()
+		if tty {
+			fmt.Print("\033[H\033[2J")
+		}
+		fmt.Printf("%s\n", color.HiBlackString("[%s] watching for changes, press Ctrl-C to stop", time.Now().Format("15:04:05")))
+		print()
+	}
+}
+
+// watchResult превращает остановку по Ctrl-C или --timeout в обычное завершение.
+func watchResult(err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil
+	}
+	return err
+}
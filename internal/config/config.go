@@ -0,0 +1,223 @@
+// Package config читает значения флагов по умолчанию из YAML-файлов.
+//
+// Пример .file-manager.yaml:
+//
+//	defaults:            # для всех команд, у которых есть такой флаг
+//	  ignore: [.git, node_modules]
+//	commands:
+//	  find-duplicates:
+//	    hash: sha256
+//	profiles:
+//	  monorepo:          # включается флагом --profile monorepo
+//	    defaults:
+//	      ignore: [.git, node_modules, vendor, dist]
+//	    commands:
+//	      code-stats:
+//	        ignore-language: json
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFile ищется в текущей директории и выше по дереву.
+const ProjectFile = ".file-manager.yaml"
+
+// Settings — значения флагов по имени: строка, число, bool или список.
+type Settings map[string]any
+
+type Section struct {
+	Defaults Settings            `yaml:"defaults"`
+	Commands map[string]Settings `yaml:"commands"`
+}
+
+type File struct {
+	Section  `yaml:",inline"`
+	Profiles map[string]Section `yaml:"profiles"`
+
+	Path string `yaml:"-"`
+}
+
+// Config — загруженные файлы в порядке возрастания приоритета.
+type Config struct {
+	Files []*File
+}
+
+// Load читает пользовательский файл ($XDG_CONFIG_HOME/file-manager/config.yaml)
+// и файл проекта. Если explicit не пуст, он используется вместо файла проекта.
+// Отсутствующие файлы пропускаются.
+func Load(explicit string) (*Config, error) {
+	var paths []string
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "file-manager", "config.yaml"))
+	}
+	if explicit != "" {
+		paths = append(paths, explicit)
+	} else if project, ok := findProjectFile(); ok {
+		paths = append(paths, project)
+	}
+
+	cfg := &Config{}
+	for _, path := range paths {
+		file, err := readFile(path)
+		if errors.Is(err, os.ErrNotExist) && path != explicit {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		cfg.Files = append(cfg.Files, file)
+	}
+	return cfg, nil
+}
+
+func findProjectFile() (string, bool) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	for {
+		path := filepath.Join(dir, ProjectFile)
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+func readFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &File{Path: path}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return file, nil
+}
+
+// value — значение флага вместе с местом, где оно задано.
+type value struct {
+	raw    any
+	source string
+	// shared — значение из defaults: его пропускают команды без такого флага.
+	shared bool
+}
+
+// resolve сливает настройки для команды. Приоритет по возрастанию:
+// пользовательский файл, файл проекта, затем профиль (из обоих файлов).
+// В каждом слое значения команды перекрывают defaults.
+func (c *Config) resolve(command, profile string) (map[string]value, error) {
+	merged := make(map[string]value)
+	apply := func(section Section, source string) {
+		for name, raw := range section.Defaults {
+			merged[name] = value{raw: raw, source: source, shared: true}
+		}
+		for name, raw := range section.Commands[command] {
+			merged[name] = value{raw: raw, source: source}
+		}
+	}
+
+	for _, file := range c.Files {
+		apply(file.Section, file.Path)
+	}
+
+	if profile == "" {
+		return merged, nil
+	}
+	found := false
+	for _, file := range c.Files {
+		if section, ok := file.Profiles[profile]; ok {
+			found = true
+			apply(section, fmt.Sprintf("%s (profile %s)", file.Path, profile))
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("unknown profile %q", profile)
+	}
+	return merged, nil
+}
+
+// Apply задаёт значения флагов cmd, которые не указаны в командной строке:
+// явно переданный флаг всегда важнее файла настроек.
+func (c *Config) Apply(cmd *cobra.Command, profile string) error {
+	command := commandName(cmd)
+	settings, err := c.resolve(command, profile)
+	if err != nil {
+		return err
+	}
+
+	var fromConfig []string
+	for name, v := range settings {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil {
+			if v.shared {
+				continue
+			}
+			return fmt.Errorf("%s: command %s has no flag --%s", v.source, command, name)
+		}
+		if flag.Changed {
+			continue
+		}
+
+		var err error
+		items, isList := v.raw.([]any)
+		if v.raw == nil {
+			err = flag.Value.Set("")
+		} else if slice, ok := flag.Value.(interface{ Replace([]string) error }); ok && isList {
+			err = slice.Replace(stringify(items))
+		} else if isList {
+			err = flag.Value.Set(strings.Join(stringify(items), ","))
+		} else {
+			err = flag.Value.Set(fmt.Sprint(v.raw))
+		}
+		if err != nil {
+			return fmt.Errorf("%s: invalid value for --%s: %w", v.source, name, err)
+		}
+		// Value.Set не отмечает флаг заданным, и cobra не увидела бы его
+		// в проверке взаимоисключающих флагов. Значение по умолчанию заданным
+		// не считается: "watch: false" не конфликтует с --by-author.
+		flag.Changed = flag.Value.String() != flag.DefValue
+		if flag.Changed {
+			fromConfig = append(fromConfig, fmt.Sprintf("--%s (%s)", name, v.source))
+		}
+	}
+
+	if err := cmd.ValidateFlagGroups(); err != nil && len(fromConfig) > 0 {
+		sort.Strings(fromConfig)
+		return fmt.Errorf("%w; set in config: %s", err, strings.Join(fromConfig, ", "))
+	}
+	return nil
+}
+
+// commandName — ключ команды в разделе commands: путь без имени программы,
+// например "code-stats history".
+func commandName(cmd *cobra.Command) string {
+	return strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+}
+
+func stringify(items []any) []string {
+	values := make([]string, len(items))
+	for i, item := range items {
+		values[i] = fmt.Sprint(item)
+	}
+	return values
+}
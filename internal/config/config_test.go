@@ -0,0 +1,173 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// testConfig — пользовательский файл и файл проекта.
+func testConfig(t *testing.T, user, project string) *Config {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := &Config{}
+	for name, data := range map[string]string{"user.yaml": user, "project.yaml": project} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Порядок файлов задаёт приоритет, поэтому они читаются не из map.
+	for _, name := range []string{"user.yaml", "project.yaml"} {
+		file, err := readFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.Files = append(cfg.Files, file)
+	}
+	return cfg
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name          string
+		user, project string
+		profile       string
+		want          string
+		wantSource    string
+	}{
+		{
+			name:       "user defaults",
+			user:       "defaults: {hash: md5}",
+			want:       "md5",
+			wantSource: "user.yaml",
+		},
+		{
+			name:       "commands override defaults in the same file",
+			user:       "defaults: {hash: md5}\ncommands: {find-duplicates: {hash: sha1}}",
+			want:       "sha1",
+			wantSource: "user.yaml",
+		},
+		{
+			name:       "commands of another command are ignored",
+			user:       "defaults: {hash: md5}\ncommands: {snapshot: {hash: sha1}}",
+			want:       "md5",
+			wantSource: "user.yaml",
+		},
+		{
+			name:       "project defaults override user commands",
+			user:       "commands: {find-duplicates: {hash: sha1}}",
+			project:    "defaults: {hash: sha256}",
+			want:       "sha256",
+			wantSource: "project.yaml",
+		},
+		{
+			name:       "profile overrides project commands",
+			user:       "profiles: {fast: {defaults: {hash: md5}}}",
+			project:    "commands: {find-duplicates: {hash: sha256}}",
+			profile:    "fast",
+			want:       "md5",
+			wantSource: "user.yaml (profile fast)",
+		},
+		{
+			name:       "project profile overrides user profile",
+			user:       "profiles: {fast: {commands: {find-duplicates: {hash: md5}}}}",
+			project:    "profiles: {fast: {defaults: {hash: sha1}}}",
+			profile:    "fast",
+			want:       "sha1",
+			wantSource: "project.yaml (profile fast)",
+		},
+		{
+			name:       "profile is not applied unless selected",
+			user:       "defaults: {hash: md5}\nprofiles: {fast: {defaults: {hash: sha1}}}",
+			want:       "md5",
+			wantSource: "user.yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := testConfig(t, tt.user, tt.project).resolve("find-duplicates", tt.profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v := settings["hash"]
+			if v.raw != tt.want || filepath.Base(v.source) != tt.wantSource {
+				t.Errorf("got %v from %q, want %v from %q", v.raw, v.source, tt.want, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestResolveUnknownProfile(t *testing.T) {
+	cfg := testConfig(t, "profiles: {fast: {}}", "")
+	if _, err := cfg.resolve("find-duplicates", "slow"); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+}
+
+func newTestCommand() *cobra.Command {
+	root := &cobra.Command{Use: "file-manager"}
+	cmd := &cobra.Command{Use: "find-duplicates", RunE: func(*cobra.Command, []string) error { return nil }}
+	cmd.Flags().String("hash", "md5", "")
+	cmd.Flags().StringP("ignore", "i", "", "")
+	cmd.Flags().StringArray("size", nil, "")
+	root.AddCommand(cmd)
+	return cmd
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    string
+		args    []string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "lists",
+			user: "defaults: {ignore: [.git, vendor]}\ncommands: {find-duplicates: {size: [+1k, -1M]}}",
+			want: map[string]string{"ignore": ".git,vendor", "size": "[+1k,-1M]"},
+		},
+		{
+			name: "command line wins",
+			user: "commands: {find-duplicates: {hash: sha1}}",
+			args: []string{"--hash", "sha512"},
+			want: map[string]string{"hash": "sha512"},
+		},
+		{
+			name: "shared default without such a flag is skipped",
+			user: "defaults: {top: 5, hash: sha1}",
+			want: map[string]string{"hash": "sha1"},
+		},
+		{
+			name:    "command setting without such a flag",
+			user:    "commands: {find-duplicates: {top: 5}}",
+			wantErr: "has no flag --top",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newTestCommand()
+			if err := cmd.Flags().Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			err := testConfig(t, tt.user, "").Apply(cmd, "")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range tt.want {
+				if got := cmd.Flags().Lookup(name).Value.String(); got != want {
+					t.Errorf("--%s: got %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
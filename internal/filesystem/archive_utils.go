@@ -0,0 +1,425 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// archiveSeparator отделяет путь архива от пути внутри него: "bundle.zip!/src/main.go".
+const archiveSeparator = "!"
+
+// maxArchiveMemory ограничивает архивы, которые приходится читать в память:
+// tar целиком и zip, лежащие внутри других архивов. Прочитанные архивы
+// остаются в памяти до конца сканирования: их записи читаются и после обхода.
+const maxArchiveMemory = 256 << 20
+
+var errArchiveTooLarge = fmt.Errorf("archive exceeds %d MiB and cannot be read into memory", maxArchiveMemory>>20)
+
+func isArchive(name string) bool {
+	name = strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveSet открывает архивы при обходе. Записи архивов читаются и после
+// завершения обхода (например, при хешировании), поэтому zip-архивы на диске
+// переоткрываются по требованию, а close закрывает то, что осталось открытым.
+type archiveSet struct {
+	mu      sync.Mutex
+	sources []*zipSource
+}
+
+// newArchiveSet возвращает nil, если обход архивов выключен.
+func newArchiveSet(enabled bool) *archiveSet {
+	if !enabled {
+		return nil
+	}
+	return &archiveSet{}
+}
+
+func (a *archiveSet) close() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, source := range a.sources {
+		source.Close()
+	}
+	a.sources = nil
+}
+
+// open возвращает содержимое архива e как fs.FS. done вызывается после обхода
+// архива: zip-файл закрывается, пока не понадобится снова.
+func (a *archiveSet) open(e walkEntry) (fsys fs.FS, done func(), err error) {
+	file, err := e.open()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	name := strings.ToLower(e.name)
+	if !strings.HasSuffix(name, ".zip") {
+		defer file.Close()
+		var r io.Reader = file
+		if strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".tgz") {
+			gz, err := gzip.NewReader(file)
+			if err != nil {
+				return nil, nil, err
+			}
+			defer gz.Close()
+			r = gz
+		}
+		fsys, err := readTar(r)
+		return fsys, func() {}, err
+	}
+
+	if _, ok := file.(io.ReaderAt); ok {
+		source := &zipSource{open: e.open, file: file, refs: 1}
+		zr, err := zip.NewReader(source, e.info.Size())
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		a.mu.Lock()
+		a.sources = append(a.sources, source)
+		a.mu.Unlock()
+		return &zipArchive{Reader: zr, source: source}, source.release, nil
+	}
+
+	// Вложенный zip не поддерживает ReaderAt: читаем его в память.
+	defer file.Close()
+	data, err := readLimited(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	return zr, func() {}, err
+}
+
+// zipSource — zip-файл, который открыт, только пока архив обходится или пока
+// открыта хотя бы одна его запись. Иначе каталог с тысячами архивов держал бы
+// тысячи дескрипторов до конца сканирования.
+type zipSource struct {
+	open func() (fs.File, error)
+	mu   sync.Mutex
+	file fs.File
+	refs int
+}
+
+func (s *zipSource) acquire() {
+	s.mu.Lock()
+	s.refs++
+	s.mu.Unlock()
+}
+
+func (s *zipSource) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refs--
+	if s.refs == 0 && s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+}
+
+// ReadAt переоткрывает файл, если он был закрыт. Читают его только открытые
+// записи, которые держат ссылку, поэтому файл не закроется посреди чтения.
+func (s *zipSource) ReadAt(p []byte, off int64) (int, error) {
+	s.mu.Lock()
+	if s.file == nil {
+		file, err := s.open()
+		if err != nil {
+			s.mu.Unlock()
+			return 0, err
+		}
+		s.file = file
+	}
+	ra, ok := s.file.(io.ReaderAt)
+	s.mu.Unlock()
+	if !ok {
+		return 0, errors.New("archive does not support random access")
+	}
+	return ra.ReadAt(p, off)
+}
+
+func (s *zipSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// zipArchive — содержимое zip-файла; открытые записи держат zipSource открытым.
+type zipArchive struct {
+	*zip.Reader
+	source *zipSource
+}
+
+func (z *zipArchive) Open(name string) (fs.File, error) {
+	z.source.acquire()
+	file, err := z.Reader.Open(name)
+	if err != nil {
+		z.source.release()
+		return nil, err
+	}
+	return &zipEntry{File: file, source: z.source}, nil
+}
+
+type zipEntry struct {
+	fs.File
+	source *zipSource
+	closed bool
+}
+
+func (f *zipEntry) Close() error {
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	defer f.source.release()
+	return f.File.Close()
+}
+
+func (f *zipEntry) ReadDir(n int) ([]fs.DirEntry, error) {
+	dir, ok := f.File.(fs.ReadDirFile)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Err: errors.New("not a directory")}
+	}
+	return dir.ReadDir(n)
+}
+
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxArchiveMemory+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxArchiveMemory {
+		return nil, errArchiveTooLarge
+	}
+	return data, nil
+}
+
+// readTar читает tar в память: формат не позволяет обращаться к файлам по имени.
+func readTar(r io.Reader) (fs.FS, error) {
+	fsys := &memFS{nodes: map[string]*memNode{
+		".": {name: ".", mode: fs.ModeDir | 0o755},
+	}}
+	var total int64
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "/"))
+		if !fs.ValidPath(name) || name == "." {
+			continue
+		}
+
+		node := &memNode{name: name, mode: hdr.FileInfo().Mode(), modTime: hdr.ModTime, sys: hdr}
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			total += hdr.Size
+			if total > maxArchiveMemory {
+				return nil, errArchiveTooLarge
+			}
+			node.data = make([]byte, hdr.Size)
+			if _, err := io.ReadFull(tr, node.data); err != nil {
+				return nil, err
+			}
+		case tar.TypeSymlink:
+			node.data = []byte(hdr.Linkname)
+		case tar.TypeDir:
+		default:
+			// Жёсткие ссылки, устройства и т.п. пропускаем.
+			continue
+		}
+		fsys.add(node)
+	}
+	return fsys, nil
+}
+
+// memFS — файловая система в памяти для содержимого tar-архивов.
+type memFS struct {
+	nodes map[string]*memNode
+}
+
+type memNode struct {
+	name     string
+	mode     fs.FileMode
+	modTime  time.Time
+	data     []byte
+	sys      any
+	children map[string]*memNode
+}
+
+func (n *memNode) Name() string               { return path.Base(n.name) }
+func (n *memNode) Size() int64                { return int64(len(n.data)) }
+func (n *memNode) Mode() fs.FileMode          { return n.mode }
+func (n *memNode) ModTime() time.Time         { return n.modTime }
+func (n *memNode) IsDir() bool                { return n.mode.IsDir() }
+func (n *memNode) Sys() any                   { return n.sys }
+func (n *memNode) Info() (fs.FileInfo, error) { return n, nil }
+func (n *memNode) Type() fs.FileMode          { return n.mode.Type() }
+
+// add добавляет запись, создавая недостающие родительские директории:
+// в tar они не обязаны присутствовать.
+func (f *memFS) add(node *memNode) {
+	if existing, ok := f.nodes[node.name]; ok && existing.IsDir() && node.IsDir() {
+		existing.mode, existing.modTime, existing.sys = node.mode, node.modTime, node.sys
+		return
+	}
+	f.nodes[node.name] = node
+
+	for child := node; child.name != "."; {
+		parent := f.dir(path.Dir(child.name))
+		parent.children[child.Name()] = child
+		child = parent
+	}
+}
+
+func (f *memFS) dir(name string) *memNode {
+	node, ok := f.nodes[name]
+	if !ok || !node.IsDir() {
+		node = &memNode{name: name, mode: fs.ModeDir | 0o755}
+		f.nodes[name] = node
+	}
+	if node.children == nil {
+		node.children = make(map[string]*memNode)
+	}
+	return node
+}
+
+func (f *memFS) Lstat(name string) (fs.FileInfo, error) {
+	node, ok := f.nodes[name]
+	if !ok || !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "lstat", Path: name, Err: fs.ErrNotExist}
+	}
+	return node, nil
+}
+
+func (f *memFS) Stat(name string) (fs.FileInfo, error) {
+	node, err := f.resolve(name, "stat")
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func (f *memFS) Open(name string) (fs.File, error) {
+	node, err := f.resolve(name, "open")
+	if err != nil {
+		return nil, err
+	}
+	return &memFile{node: node, r: bytes.NewReader(node.data)}, nil
+}
+
+func (f *memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	node, err := f.resolve(name, "readdir")
+	if err != nil {
+		return nil, err
+	}
+	if !node.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return node.entries(), nil
+}
+
+// resolve находит запись, проходя по символическим ссылкам внутри архива.
+func (f *memFS) resolve(name, op string) (*memNode, error) {
+	node, ok := f.nodes[name]
+	for hops := 0; ok && node.mode&fs.ModeSymlink != 0; hops++ {
+		if hops == 40 {
+			return nil, &fs.PathError{Op: op, Path: name, Err: errors.New("too many levels of symbolic links")}
+		}
+		target := string(node.data)
+		if !path.IsAbs(target) {
+			target = path.Join(path.Dir(node.name), target)
+		}
+		node, ok = f.nodes[path.Clean(strings.TrimPrefix(target, "/"))]
+	}
+	if !ok || !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return node, nil
+}
+
+func (n *memNode) entries() []fs.DirEntry {
+	entries := make([]fs.DirEntry, 0, len(n.children))
+	for _, child := range n.children {
+		entries = append(entries, child)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries
+}
+
+type memFile struct {
+	node    *memNode
+	r       *bytes.Reader
+	entries []fs.DirEntry
+	dirRead bool
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.node, nil }
+func (f *memFile) Close() error               { return nil }
+
+func (f *memFile) Read(p []byte) (int, error) {
+	if f.node.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: f.node.name, Err: errors.New("is a directory")}
+	}
+	return f.r.Read(p)
+}
+
+// ReadAt позволяет открывать zip, лежащие внутри tar, без копирования.
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	return f.r.ReadAt(p, off)
+}
+
+func (f *memFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !f.node.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: f.node.name, Err: errors.New("not a directory")}
+	}
+	if !f.dirRead {
+		f.entries, f.dirRead = f.node.entries(), true
+	}
+	if n <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(f.entries) {
+		n = len(f.entries)
+	}
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+	return entries, nil
+}
@@ -0,0 +1,234 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// archiveFile — запись архива для тестов; пустое имя ссылки — обычный файл.
+type archiveFile struct {
+	name, data, link string
+}
+
+func zipBytes(t *testing.T, files ...archiveFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarGzBytes(t *testing.T, files ...archiveFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.data)), Typeflag: tar.TypeReg}
+		if f.link != "" {
+			hdr = &tar.Header{Name: f.name, Mode: 0o777, Linkname: f.link, Typeflag: tar.TypeSymlink}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if f.link == "" {
+			if _, err := tw.Write([]byte(f.data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func archiveFixture(t *testing.T) fstest.MapFS {
+	inner := zipBytes(t, archiveFile{name: "inner.go", data: "package inner\n"})
+	return fstest.MapFS{
+		"main.go": {Data: []byte("package main\n")},
+		"bundle.zip": {Data: zipBytes(t,
+			archiveFile{name: "src/main.go", data: "package main\n"},
+			archiveFile{name: "README.md", data: "# Bundle\n"},
+		)},
+		"release.tar.gz": {Data: tarGzBytes(t,
+			archiveFile{name: "app/app.go", data: "package app\n"},
+			archiveFile{name: "app/current.go", link: "app.go"},
+			archiveFile{name: "nested.zip", data: string(inner)},
+		)},
+	}
+}
+
+func TestSearchFilesFSArchives(t *testing.T) {
+	matcher, err := NewMatcher([]string{"*.go"}, MatchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	search := func(archives bool) []string {
+		results, err := SearchFilesFS(context.Background(), archiveFixture(t), SearchOptions{
+			ScanOptions: ScanOptions{Archives: archives},
+			Matcher:     matcher,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var paths []string
+		for _, r := range results {
+			paths = append(paths, r.Path)
+		}
+		return paths
+	}
+
+	if got := search(false); !reflect.DeepEqual(got, []string{"main.go"}) {
+		t.Errorf("without archives: got %q", got)
+	}
+	want := []string{
+		"bundle.zip!/src/main.go",
+		"main.go",
+		"release.tar.gz!/app/app.go",
+		"release.tar.gz!/app/current.go",
+		"release.tar.gz!/nested.zip!/inner.go",
+	}
+	if got := search(true); !reflect.DeepEqual(got, want) {
+		t.Errorf("with archives: got %q, want %q", got, want)
+	}
+}
+
+func TestFindDuplicatesFSArchives(t *testing.T) {
+	groups, err := FindDuplicatesFS(context.Background(), archiveFixture(t), ScanOptions{Archives: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 {
+		t.Fatalf("got %q, want one group", groups)
+	}
+	sort.Strings(groups[0])
+	// Ссылка внутри tar не считается копией своей цели.
+	if want := []string{"bundle.zip!/src/main.go", "main.go"}; !reflect.DeepEqual(groups[0], want) {
+		t.Errorf("got %q, want %q", groups[0], want)
+	}
+}
+
+func TestCountCodeLinesFSArchives(t *testing.T) {
+	stats, err := CountCodeLinesFS(context.Background(), archiveFixture(t), nil, ScanOptions{Archives: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"main.go", "bundle.zip!/src/main.go", "release.tar.gz!/nested.zip!/inner.go"} {
+		if _, ok := stats.Files[path]; !ok {
+			t.Errorf("%s not counted", path)
+		}
+	}
+	if got := stats.Languages["Markdown"]; got == nil || got.TotalLines != 1 {
+		t.Errorf("Markdown inside zip: got %+v", got)
+	}
+}
+
+func TestFindDuplicatesFSBrokenArchive(t *testing.T) {
+	fsys := fstest.MapFS{
+		"broken.zip": {Data: []byte("not a zip")},
+		"a.txt":      {Data: []byte("x")},
+		"b.txt":      {Data: []byte("x")},
+	}
+	groups, err := FindDuplicatesFS(context.Background(), fsys, ScanOptions{Archives: true})
+	var scanErrs ScanErrors
+	if !errors.As(err, &scanErrs) || len(scanErrs) != 1 || scanErrs[0].Path != "broken.zip" {
+		t.Fatalf("got error %v, want a warning for broken.zip", err)
+	}
+	if len(groups) != 1 || len(groups[0]) != 2 {
+		t.Errorf("got %q, want a.txt and b.txt", groups)
+	}
+}
+
+// countingFS считает открытые и ещё не закрытые файлы.
+type countingFS struct {
+	fstest.MapFS
+	mu   sync.Mutex
+	open int
+	max  int
+}
+
+type countedFile struct {
+	fs.File
+	fsys *countingFS
+}
+
+func (c *countingFS) Open(name string) (fs.File, error) {
+	f, err := c.MapFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.open++
+	c.max = max(c.max, c.open)
+	c.mu.Unlock()
+	return &countedFile{File: f, fsys: c}, nil
+}
+
+func (f *countedFile) Close() error {
+	f.fsys.mu.Lock()
+	f.fsys.open--
+	f.fsys.mu.Unlock()
+	return f.File.Close()
+}
+
+func (f *countedFile) ReadAt(p []byte, off int64) (int, error) {
+	return f.File.(io.ReaderAt).ReadAt(p, off)
+}
+
+func TestArchivesReleaseZipFiles(t *testing.T) {
+	fsys := &countingFS{MapFS: fstest.MapFS{}}
+	for i := 0; i < 50; i++ {
+		fsys.MapFS[fmt.Sprintf("a%02d.zip", i)] = &fstest.MapFile{
+			Data: zipBytes(t, archiveFile{name: "f.txt", data: fmt.Sprintf("content %d", i%2)}),
+		}
+	}
+	groups, err := FindDuplicatesFS(context.Background(), fsys, ScanOptions{Archives: true, Concurrency: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := 0
+	for _, group := range groups {
+		for _, path := range group {
+			if strings.HasSuffix(path, "!/f.txt") {
+				entries++
+			}
+		}
+	}
+	if entries != 50 {
+		t.Errorf("%d archive entries in duplicate groups, want 50", entries)
+	}
+	if fsys.open != 0 {
+		t.Errorf("%d files left open", fsys.open)
+	}
+	if fsys.max > 4 {
+		t.Errorf("up to %d files open at once, want archives closed after the walk", fsys.max)
+	}
+}
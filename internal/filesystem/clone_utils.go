@@ -0,0 +1,397 @@
+package filesystem
+
+import (
+	"context"
+	"hash/fnv"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// DefaultCloneLines — минимальный размер клона в строках кода по умолчанию.
+const DefaultCloneLines = 6
+
+// maxCloneOccurrences ограничивает число сравниваемых вхождений одного окна:
+// шаблонный код, повторённый сотни раз, иначе даёт квадратичное число пар.
+const maxCloneOccurrences = 64
+
+// CloneLocation — место, где встречается фрагмент.
+type CloneLocation struct {
+	Path string
+	// StartLine и EndLine — первая и последняя строка фрагмента в файле (с 1).
+	StartLine int
+	EndLine   int
+	// FirstCodeLine и LastCodeLine — те же границы в нормализованных строках
+	// кода файла (с 1): по ним TotalDuplicatedLines находит вложенные клоны.
+	// 0, если границы неизвестны.
+	FirstCodeLine int
+	LastCodeLine  int
+}
+
+// Clone — фрагмент кода, повторённый в нескольких местах. Более короткий
+// клон может лежать внутри более длинного, если часть фрагмента встречается
+// ещё где-то; общий итог по таким клонам считает TotalDuplicatedLines.
+type Clone struct {
+	// Lines — длина фрагмента в нормализованных строках кода: без комментариев,
+	// пустых строк и строк из одних скобок.
+	Lines     int
+	Locations []CloneLocation
+	// Truncated — фрагмент повторяется больше maxCloneOccurrences раз,
+	// и сравнивались только первые вхождения: часть копий может не попасть
+	// в Locations.
+	Truncated bool
+}
+
+// DuplicatedLines — строки кода, которые можно убрать, оставив одну копию.
+func (c Clone) DuplicatedLines() int {
+	if len(c.Locations) < 2 {
+		return 0
+	}
+	return c.Lines * (len(c.Locations) - 1)
+}
+
+// TotalDuplicatedLines возвращает число строк кода, которые можно убрать из
+// всех клонов, оставив в каждом первую копию. Строка, входящая в несколько
+// вложенных клонов, считается один раз. Копии без FirstCodeLine и
+// LastCodeLine добавляют Lines строк без проверки пересечений.
+func TotalDuplicatedLines(clones []Clone) int {
+	total := 0
+	covered := make(map[string]map[int]bool)
+	for _, clone := range clones {
+		if len(clone.Locations) < 2 {
+			continue
+		}
+		for _, loc := range clone.Locations[1:] {
+			if loc.FirstCodeLine <= 0 || loc.LastCodeLine < loc.FirstCodeLine {
+				total += clone.Lines
+				continue
+			}
+			if covered[loc.Path] == nil {
+				covered[loc.Path] = make(map[int]bool)
+			}
+			for i := loc.FirstCodeLine; i <= loc.LastCodeLine; i++ {
+				covered[loc.Path][i] = true
+			}
+		}
+	}
+	for _, lines := range covered {
+		total += len(lines)
+	}
+	return total
+}
+
+// cloneFile — нормализованные строки кода файла.
+type cloneFile struct {
+	path string
+	// lines — номера строк в файле, hashes — хеши нормализованных строк.
+	lines  []int
+	hashes []uint64
+}
+
+// FindClones ищет фрагменты не короче minLines строк кода (по умолчанию
+// DefaultCloneLines), которые повторяются в одном или нескольких файлах.
+// Строки сравниваются без комментариев и пробельных символов. Сгенерированные
+// и сторонние файлы пропускаются, если их не включают ScanOptions. Клоны
+// отсортированы по числу повторённых строк.
+func FindClones(ctx context.Context, root string, minLines int, ignoreLanguages []string, opts ScanOptions) ([]Clone, error) {
+	return findClones(ctx, hostTarget(root), minLines, ignoreLanguages, opts)
+}
+
+// FindClonesFS работает как FindClones, но обходит fsys.
+func FindClonesFS(ctx context.Context, fsys fs.FS, minLines int, ignoreLanguages []string, opts ScanOptions) ([]Clone, error) {
+	return findClones(ctx, fsTarget(fsys), minLines, ignoreLanguages, opts)
+}
+
+func findClones(ctx context.Context, target scanTarget, minLines int, ignoreLanguages []string, opts ScanOptions) ([]Clone, error) {
+	if minLines <= 0 {
+		minLines = DefaultCloneLines
+	}
+	ignoredLangs := make(map[string]bool)
+	for _, lang := range ignoreLanguages {
+		ignoredLangs[strings.ToLower(lang)] = true
+	}
+
+	var (
+		mu    sync.Mutex
+		files []*cloneFile
+	)
+	opts.Progress.SetPhase("reading", 0)
+	errs, err := scanFiles(ctx, target, opts, func(e walkEntry) func() error {
+		lang := getLanguage(e.path, ignoredLangs)
+		if lang == "" {
+			return nil
+		}
+		return func() error {
+			file, err := readCloneFile(e, lang, ignoredLangs, opts)
+			if err != nil {
+				return err
+			}
+			if file != nil && len(file.hashes) >= minLines {
+				mu.Lock()
+				files = append(files, file)
+				mu.Unlock()
+			}
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Progress.SetPhase("matching", 0)
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	return matchClones(files, minLines), errs.finish(ctx)
+}
+
+// readCloneFile возвращает нормализованные строки кода файла или nil, если
+// файл не входит в поиск по классу. Встроенные фрагменты на языках из ignored
+// пропускаются.
+func readCloneFile(e walkEntry, lang string, ignored map[string]bool, opts ScanOptions) (*cloneFile, error) {
+	f, err := e.fsys.Open(e.name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	file := &cloneFile{path: e.path}
+	hookFor := func(lang string) (lineHook, bool) {
+		if ignored[strings.ToLower(lang)] {
+			return lineHook{}, false
+		}
+		marker := lineComments[lang]
+		var quotes string
+		if rules := estimates[lang]; rules != nil {
+			quotes = rules.quotes
+		}
+		return lineHook{onCode: func(n int, line string) {
+			normalized := normalizeCloneLine(line, marker, quotes)
+			if normalized == "" {
+				return
+			}
+			h := fnv.New64a()
+			h.Write([]byte(normalized))
+			file.lines = append(file.lines, n)
+			file.hashes = append(file.hashes, h.Sum64())
+		}}, true
+	}
+
+	generated, err := scanLines(f, lang, hookFor)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.countsClass(classifyFile(e.rel, generated)) {
+		return nil, nil
+	}
+	if embedsLanguages(lang) {
+		// Части файла разбираются по очереди; окна должны идти в порядке строк.
+		sort.Sort(file)
+	}
+	return file, nil
+}
+
+func (f *cloneFile) Len() int           { return len(f.lines) }
+func (f *cloneFile) Less(i, j int) bool { return f.lines[i] < f.lines[j] }
+func (f *cloneFile) Swap(i, j int) {
+	f.lines[i], f.lines[j] = f.lines[j], f.lines[i]
+	f.hashes[i], f.hashes[j] = f.hashes[j], f.hashes[i]
+}
+
+// normalizeCloneLine убирает из строки кода комментарий в конце и все пробельные
+// символы. Строки без букв и цифр ("}", "});") возвращаются пустыми: иначе
+// они склеивают несвязанные фрагменты в клоны.
+func normalizeCloneLine(line, marker, quotes string) string {
+	if marker != "" {
+		line = cutLineComment(line, marker, quotes)
+	}
+	if strings.IndexFunc(line, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0 {
+		return ""
+	}
+	return strings.Join(strings.Fields(line), "")
+}
+
+// cutLineComment обрезает строку перед marker, если он стоит вне строкового литерала.
+func cutLineComment(line, marker, quotes string) string {
+	if !strings.Contains(line, marker) {
+		return line
+	}
+	var quote rune
+	escaped := false
+	for i, r := range line {
+		switch {
+		case quote == 0:
+			if strings.HasPrefix(line[i:], marker) {
+				return line[:i]
+			}
+			if strings.ContainsRune(quotes, r) {
+				quote = r
+			}
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == quote:
+			quote = 0
+		}
+	}
+	return line
+}
+
+// clonePos — окно из minLines строк, начинающееся со строки start файла file.
+type clonePos struct {
+	file, start int
+}
+
+// cloneBase — основание полиномиального (Рабина — Карпа) хеша окна.
+const cloneBase = 1099511628211
+
+// matchClones находит совпадающие окна по скользящему хешу, продлевает каждую
+// пару совпадений до максимальной длины и объединяет пары с одинаковым
+// содержимым в один клон. Клоны, все копии которых лежат внутри копий более
+// длинного клона, отбрасываются.
+func matchClones(files []*cloneFile, minLines int) []Clone {
+	var power uint64 = 1
+	for i := 1; i < minLines; i++ {
+		power *= cloneBase
+	}
+	windows := make(map[uint64][]clonePos)
+	for fi, file := range files {
+		var h uint64
+		for i, lh := range file.hashes {
+			if i >= minLines {
+				h -= file.hashes[i-minLines] * power
+			}
+			h = h*cloneBase + lh
+			if i >= minLines-1 {
+				windows[h] = append(windows[h], clonePos{fi, i - minLines + 1})
+			}
+		}
+	}
+
+	type cloneKey struct {
+		hash  uint64
+		lines int
+	}
+	groups := make(map[cloneKey]map[CloneLocation]bool)
+	truncated := make(map[cloneKey]bool)
+	for _, positions := range windows {
+		if len(positions) < 2 {
+			continue
+		}
+		cut := len(positions) > maxCloneOccurrences
+		positions = positions[:min(len(positions), maxCloneOccurrences)]
+		for i, a := range positions {
+			for _, b := range positions[i+1:] {
+				length := extendClone(files, a, b, minLines)
+				if length == 0 {
+					continue
+				}
+				key := cloneKey{blockHash(files[a.file].hashes[a.start : a.start+length]), length}
+				if groups[key] == nil {
+					groups[key] = make(map[CloneLocation]bool)
+				}
+				groups[key][files[a.file].location(a.start, length)] = true
+				groups[key][files[b.file].location(b.start, length)] = true
+				truncated[key] = truncated[key] || cut
+			}
+		}
+	}
+
+	// Копии всех клонов по файлам — чтобы найти вложенные клоны.
+	type span struct{ first, last, lines int }
+	byPath := make(map[string][]span)
+	for key, locations := range groups {
+		for loc := range locations {
+			byPath[loc.Path] = append(byPath[loc.Path], span{loc.FirstCodeLine, loc.LastCodeLine, key.lines})
+		}
+	}
+	nested := func(loc CloneLocation, lines int) bool {
+		for _, outer := range byPath[loc.Path] {
+			if outer.lines > lines && outer.first <= loc.FirstCodeLine && loc.LastCodeLine <= outer.last {
+				return true
+			}
+		}
+		return false
+	}
+
+	clones := make([]Clone, 0, len(groups))
+	for key, locations := range groups {
+		inside := true
+		for loc := range locations {
+			if !nested(loc, key.lines) {
+				inside = false
+				break
+			}
+		}
+		if inside {
+			continue
+		}
+		clone := Clone{Lines: key.lines, Truncated: truncated[key]}
+		for loc := range locations {
+			clone.Locations = append(clone.Locations, loc)
+		}
+		sort.Slice(clone.Locations, func(i, j int) bool {
+			a, b := clone.Locations[i], clone.Locations[j]
+			if a.Path != b.Path {
+				return a.Path < b.Path
+			}
+			return a.StartLine < b.StartLine
+		})
+		clones = append(clones, clone)
+	}
+	sort.Slice(clones, func(i, j int) bool {
+		a, b := clones[i], clones[j]
+		if a.DuplicatedLines() != b.DuplicatedLines() {
+			return a.DuplicatedLines() > b.DuplicatedLines()
+		}
+		if a.Lines != b.Lines {
+			return a.Lines > b.Lines
+		}
+		return a.Locations[0].Path < b.Locations[0].Path ||
+			a.Locations[0].Path == b.Locations[0].Path && a.Locations[0].StartLine < b.Locations[0].StartLine
+	})
+	return clones
+}
+
+// extendClone возвращает длину совпадения окон a и b, продлённого вперёд,
+// или 0, если окна на самом деле различаются (коллизия хеша), перекрываются
+// или совпадение начинается раньше и уже найдено с предыдущего окна.
+func extendClone(files []*cloneFile, a, b clonePos, minLines int) int {
+	x, y := files[a.file].hashes, files[b.file].hashes
+	if a.start > 0 && b.start > 0 && x[a.start-1] == y[b.start-1] {
+		return 0
+	}
+	limit := min(len(x)-a.start, len(y)-b.start)
+	if a.file == b.file {
+		// Копии внутри одного файла не должны перекрываться.
+		limit = min(limit, b.start-a.start)
+	}
+	length := 0
+	for length < limit && x[a.start+length] == y[b.start+length] {
+		length++
+	}
+	if length < minLines {
+		return 0
+	}
+	return length
+}
+
+func blockHash(hashes []uint64) uint64 {
+	var h uint64
+	for _, lh := range hashes {
+		h = h*cloneBase + lh
+	}
+	return h
+}
+
+func (f *cloneFile) location(start, length int) CloneLocation {
+	return CloneLocation{
+		Path:      f.path,
+		StartLine: f.lines[start],
+		EndLine:   f.lines[start+length-1],
+		// Номера строк кода с 1, как и строк файла.
+		FirstCodeLine: start + 1,
+		LastCodeLine:  start + length,
+	}
+}
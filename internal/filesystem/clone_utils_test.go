@@ -0,0 +1,127 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// goLines возвращает файл Go из n разных строк кода, начиная с номера from.
+func goLines(from, n int) string {
+	var b strings.Builder
+	b.WriteString("package p\n")
+	for i := from; i < from+n; i++ {
+		fmt.Fprintf(&b, "var v%d = %d\n", i, i*i)
+	}
+	return b.String()
+}
+
+func TestFindClonesFSNested(t *testing.T) {
+	// a и b совпадают целиком (9 строк с package), c — только в первых 7.
+	fsys := fstest.MapFS{
+		"a.go": {Data: []byte(goLines(0, 8))},
+		"b.go": {Data: []byte(goLines(0, 8))},
+		"c.go": {Data: []byte(goLines(0, 6) + goLines(100, 4))},
+	}
+	clones, err := FindClonesFS(context.Background(), fsys, 6, nil, ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clones) != 2 {
+		t.Fatalf("got %d clones, want 2: %+v", len(clones), clones)
+	}
+	// Клоны отсортированы по DuplicatedLines: 7×2 строк больше, чем 9×1.
+	if clones[0].Lines != 7 || len(clones[0].Locations) != 3 {
+		t.Errorf("first clone: got %d lines in %d places, want 7 lines in all files", clones[0].Lines, len(clones[0].Locations))
+	}
+	if clones[1].Lines != 9 || len(clones[1].Locations) != 2 {
+		t.Errorf("second clone: got %d lines in %d places, want a.go and b.go with 9 lines", clones[1].Lines, len(clones[1].Locations))
+	}
+	// b.go целиком (9) и первые 7 строк c.go; 7 строк b.go уже учтены.
+	if got := TotalDuplicatedLines(clones); got != 16 {
+		t.Errorf("total duplicated lines %d, want 16", got)
+	}
+}
+
+func TestTotalDuplicatedLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		clones []Clone
+		want   int
+	}{
+		{name: "no clones"},
+		{
+			name:   "fewer than two locations",
+			clones: []Clone{{Lines: 5}, {Lines: 5, Locations: []CloneLocation{{Path: "a.go", FirstCodeLine: 1, LastCodeLine: 5}}}},
+		},
+		{
+			name: "nested clones share lines",
+			clones: []Clone{
+				{Lines: 4, Locations: []CloneLocation{
+					{Path: "a.go", FirstCodeLine: 1, LastCodeLine: 4},
+					{Path: "b.go", FirstCodeLine: 1, LastCodeLine: 4},
+				}},
+				{Lines: 2, Locations: []CloneLocation{
+					{Path: "a.go", FirstCodeLine: 1, LastCodeLine: 2},
+					{Path: "b.go", FirstCodeLine: 1, LastCodeLine: 2},
+					{Path: "c.go", FirstCodeLine: 3, LastCodeLine: 4},
+				}},
+			},
+			want: 6,
+		},
+		{
+			name: "unknown code lines",
+			clones: []Clone{{Lines: 3, Locations: []CloneLocation{
+				{Path: "a.go", StartLine: 1, EndLine: 3},
+				{Path: "a.go", StartLine: 10, EndLine: 12},
+				{Path: "b.go", StartLine: 1, EndLine: 3},
+			}}},
+			want: 6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TotalDuplicatedLines(tt.clones); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindClonesFSSkipsSubsumed(t *testing.T) {
+	// Повторяющийся блок внутри пары файлов со сдвигом: короткое совпадение
+	// под другим выравниванием целиком лежит внутри основного клона.
+	block := "x := 1\ny := 2\n"
+	src := "package p\nfunc f() {\n" + strings.Repeat(block, 5) + "}\n"
+	fsys := fstest.MapFS{
+		"a.go": {Data: []byte(src)},
+		"b.go": {Data: []byte(src)},
+	}
+	clones, err := FindClonesFS(context.Background(), fsys, 4, nil, ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Повторы блока внутри файлов тоже лежат внутри копий a.go и b.go.
+	if len(clones) != 1 || clones[0].Lines != 12 || len(clones[0].Locations) != 2 {
+		t.Errorf("got %+v, want only the 12-line clone of a.go and b.go", clones)
+	}
+}
+
+func TestFindClonesFSTruncated(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for i := 0; i < maxCloneOccurrences+6; i++ {
+		fsys[fmt.Sprintf("f%03d.go", i)] = &fstest.MapFile{Data: []byte(goLines(0, 6))}
+	}
+	clones, err := FindClonesFS(context.Background(), fsys, 6, nil, ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clones) != 1 {
+		t.Fatalf("got %d clones, want 1", len(clones))
+	}
+	if !clones[0].Truncated || len(clones[0].Locations) != maxCloneOccurrences {
+		t.Errorf("got %d locations, truncated %v; want %d and true", len(clones[0].Locations), clones[0].Truncated, maxCloneOccurrences)
+	}
+}
@@ -0,0 +1,150 @@
+package filesystem
+
+import "sort"
+
+// LanguageDelta — изменение статистики языка или файла между двумя деревьями.
+type LanguageDelta struct {
+	Language string
+	Old, New LanguageStat
+}
+
+// Delta возвращает New - Old по каждому счётчику.
+func (d LanguageDelta) Delta() LanguageStat {
+	return LanguageStat{
+		TotalLines:   d.New.TotalLines - d.Old.TotalLines,
+		CommentLines: d.New.CommentLines - d.Old.CommentLines,
+		BlankLines:   d.New.BlankLines - d.Old.BlankLines,
+		CodeLines:    d.New.CodeLines - d.Old.CodeLines,
+	}
+}
+
+type FileDelta struct {
+	// Path — путь от корня сканирования со слешами.
+	Path string
+	LanguageDelta
+	// Added и Removed — файл есть только в новом или только в старом дереве.
+	Added, Removed bool
+	// Embedded — запись о фрагментах на языке Language внутри файла
+	// (см. FileStat.Embedded), а не об основной части файла.
+	Embedded bool
+}
+
+type CodeStatsDiff struct {
+	// Languages и Files содержат только изменившиеся записи,
+	// по убыванию модуля изменения строк кода.
+	Languages []LanguageDelta
+	Files     []FileDelta
+}
+
+// DiffCodeStats сравнивает статистику двух деревьев по языкам и по файлам.
+// Файл, сменивший язык, попадает в Files дважды: как удалённый и как добавленный.
+// Встроенные языки файла дают отдельные записи с Embedded, поэтому изменения
+// в Files по каждому языку складываются в изменение из Languages.
+func DiffCodeStats(before, after *CodeStats) *CodeStatsDiff {
+	diff := &CodeStatsDiff{}
+
+	languages := make(map[string]*LanguageDelta)
+	for lang, stat := range before.Languages {
+		languages[lang] = &LanguageDelta{Language: lang, Old: *stat}
+	}
+	for lang, stat := range after.Languages {
+		if d, ok := languages[lang]; ok {
+			d.New = *stat
+		} else {
+			languages[lang] = &LanguageDelta{Language: lang, New: *stat}
+		}
+	}
+	for _, d := range languages {
+		if d.Old != d.New {
+			diff.Languages = append(diff.Languages, *d)
+		}
+	}
+
+	for rel, old := range before.Files {
+		cur, ok := after.Files[rel]
+		removed := !ok || cur.Language != old.Language
+		switch {
+		case removed:
+			diff.Files = append(diff.Files, FileDelta{
+				Path:          rel,
+				LanguageDelta: LanguageDelta{Language: old.Language, Old: old.LanguageStat},
+				Removed:       true,
+			})
+		case cur.LanguageStat != old.LanguageStat:
+			diff.Files = append(diff.Files, FileDelta{
+				Path:          rel,
+				LanguageDelta: LanguageDelta{Language: old.Language, Old: old.LanguageStat, New: cur.LanguageStat},
+			})
+		}
+		for lang, stat := range old.Embedded {
+			d := FileDelta{Path: rel, LanguageDelta: LanguageDelta{Language: lang, Old: *stat}, Embedded: true}
+			if removed {
+				d.Removed = true
+			} else if next := cur.Embedded[lang]; next != nil {
+				d.New = *next
+			}
+			if d.Old != d.New || d.Removed {
+				diff.Files = append(diff.Files, d)
+			}
+		}
+		if removed {
+			continue
+		}
+		for lang, stat := range cur.Embedded {
+			if _, ok := old.Embedded[lang]; !ok {
+				diff.Files = append(diff.Files, FileDelta{Path: rel, LanguageDelta: LanguageDelta{Language: lang, New: *stat}, Embedded: true})
+			}
+		}
+	}
+	for rel, cur := range after.Files {
+		if old, ok := before.Files[rel]; !ok || old.Language != cur.Language {
+			diff.Files = append(diff.Files, FileDelta{
+				Path:          rel,
+				LanguageDelta: LanguageDelta{Language: cur.Language, New: cur.LanguageStat},
+				Added:         true,
+			})
+			for lang, stat := range cur.Embedded {
+				diff.Files = append(diff.Files, FileDelta{
+					Path:          rel,
+					LanguageDelta: LanguageDelta{Language: lang, New: *stat},
+					Added:         true,
+					Embedded:      true,
+				})
+			}
+		}
+	}
+
+	sort.Slice(diff.Languages, func(i, j int) bool {
+		a, b := diff.Languages[i], diff.Languages[j]
+		if x, y := absInt(a.Delta().CodeLines), absInt(b.Delta().CodeLines); x != y {
+			return x > y
+		}
+		return a.Language < b.Language
+	})
+	sort.Slice(diff.Files, func(i, j int) bool {
+		a, b := diff.Files[i], diff.Files[j]
+		if x, y := absInt(a.Delta().CodeLines), absInt(b.Delta().CodeLines); x != y {
+			return x > y
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		// Удаление файла перед его добавлением под новым языком,
+		// основная часть файла перед встроенными языками.
+		if a.Removed != b.Removed {
+			return a.Removed
+		}
+		if a.Embedded != b.Embedded {
+			return !a.Embedded
+		}
+		return a.Language < b.Language
+	})
+	return diff
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
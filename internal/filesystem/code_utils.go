@@ -3,9 +3,9 @@ package filesystem
 import (
 	"bufio"
 	"bytes"
-	"github.com/SHCDevelops/file-manager/lib/utils"
+	"context"
 	"io"
-	"os"
+	"io/fs"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -17,45 +17,156 @@ const (
 
 type CodeStats struct {
 	Languages map[string]*LanguageStat
-	mu        sync.Mutex
+	// Files — статистика по файлам; ключ — путь от корня сканирования со слешами.
+	Files map[string]*FileStat
+	// Excluded — сгенерированные и сторонние файлы, не вошедшие в Languages и Files.
+	Excluded map[FileClass]*ClassStat
+	mu       sync.Mutex
 }
 
 type LanguageStat struct {
 	TotalLines   int
 	CommentLines int
-	CodeLines    int
+	BlankLines   int
+	// CodeLines — строки, которые не являются ни комментариями, ни пустыми.
+	CodeLines int
+
+	// Метрики сложности заполняются только при ScanOptions.Complexity. Для Go
+	// они точные (go/ast), для остальных языков — оценка по ключевым словам
+	// и отступам (см. estimator).
+	Functions int
+	// Branches — число ветвлений: if, циклы, case, catch, && и ||.
+	Branches int
+	// Complexity — суммарная цикломатическая сложность функций (Functions + Branches).
+	Complexity int
+	// MaxComplexity — сложность самой сложной функции; известна только для Go.
+	MaxComplexity int
+	// MaxNesting — наибольшая вложенность: для Go — управляющих конструкций,
+	// для остальных языков — уровень отступа.
+	MaxNesting int
+	// Indentation — число строк кода по уровню отступа; последний элемент
+	// включает все более глубокие уровни.
+	Indentation [maxIndentLevel + 1]int
 }
 
-func CountCodeLines(root string, ignoreList []string, ignoreLanguages []string) (*CodeStats, error) {
-	stats := &CodeStats{
+func (s *LanguageStat) merge(other LanguageStat) {
+	s.TotalLines += other.TotalLines
+	s.CommentLines += other.CommentLines
+	s.BlankLines += other.BlankLines
+	s.CodeLines += other.CodeLines
+	s.Functions += other.Functions
+	s.Branches += other.Branches
+	s.Complexity += other.Complexity
+	s.MaxComplexity = max(s.MaxComplexity, other.MaxComplexity)
+	s.MaxNesting = max(s.MaxNesting, other.MaxNesting)
+	for i, n := range other.Indentation {
+		s.Indentation[i] += n
+	}
+}
+
+type FileStat struct {
+	Language string
+	Class    FileClass
+	LanguageStat
+	// FunctionStats заполняется только для Go при ScanOptions.Complexity.
+	FunctionStats []FunctionStat
+	// Embedded — фрагменты на других языках: <script> и <style> в HTML, блоки
+	// кода в Markdown, ячейки кода в Jupyter Notebook. Их строки не входят
+	// в LanguageStat файла.
+	Embedded map[string]*LanguageStat
+}
+
+type ClassStat struct {
+	Files int
+	LanguageStat
+}
+
+func newCodeStats() *CodeStats {
+	return &CodeStats{
 		Languages: make(map[string]*LanguageStat),
+		Files:     make(map[string]*FileStat),
+		Excluded:  make(map[FileClass]*ClassStat),
+	}
+}
+
+// add учитывает разобранный файл; если counted ложно, файл попадает только
+// в Excluded. Безопасен для вызова из нескольких горутин.
+func (s *CodeStats) add(rel string, file FileStat, counted bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !counted {
+		if _, exists := s.Excluded[file.Class]; !exists {
+			s.Excluded[file.Class] = &ClassStat{}
+		}
+		s.Excluded[file.Class].Files++
+		s.Excluded[file.Class].merge(file.LanguageStat)
+		for _, stat := range file.Embedded {
+			s.Excluded[file.Class].merge(*stat)
+		}
+		return
 	}
+	s.addLanguage(file.Language, file.LanguageStat)
+	for lang, stat := range file.Embedded {
+		s.addLanguage(lang, *stat)
+	}
+	if len(file.FunctionStats) > 0 {
+		// Срез может быть общим для одинаковых файлов (см. treeCounter).
+		functions := make([]FunctionStat, len(file.FunctionStats))
+		for i, fn := range file.FunctionStats {
+			fn.Path = rel
+			functions[i] = fn
+		}
+		file.FunctionStats = functions
+	}
+	s.Files[rel] = &file
+}
+
+func (s *CodeStats) addLanguage(lang string, stat LanguageStat) {
+	if _, exists := s.Languages[lang]; !exists {
+		s.Languages[lang] = &LanguageStat{}
+	}
+	s.Languages[lang].merge(stat)
+}
+
+// CountCodeLines считает строки кода по языкам. Файлы, которые не удалось
+// прочитать, пропускаются и возвращаются в ScanErrors вместе с результатом.
+// При отмене ctx возвращается статистика по уже разобранным файлам вместе с ctx.Err().
+func CountCodeLines(ctx context.Context, root string, ignoreLanguages []string, opts ScanOptions) (*CodeStats, error) {
+	return countCodeLines(ctx, hostTarget(root), ignoreLanguages, opts)
+}
+
+// CountCodeLinesFS работает как CountCodeLines, но обходит fsys.
+func CountCodeLinesFS(ctx context.Context, fsys fs.FS, ignoreLanguages []string, opts ScanOptions) (*CodeStats, error) {
+	return countCodeLines(ctx, fsTarget(fsys), ignoreLanguages, opts)
+}
+
+func countCodeLines(ctx context.Context, target scanTarget, ignoreLanguages []string, opts ScanOptions) (*CodeStats, error) {
+	stats := newCodeStats()
 	ignoredLangs := make(map[string]bool)
 	for _, lang := range ignoreLanguages {
 		ignoredLangs[strings.ToLower(lang)] = true
 	}
 
 	var wg sync.WaitGroup
-	var mu sync.Mutex
-	var errors []error
+	errs := newErrorCollector(opts)
+	opts.Progress.SetPhase("counting", 0)
+
+	semaphore := make(chan struct{}, opts.concurrency(10)) // По умолчанию пул из 10 горутин
 
-	semaphore := make(chan struct{}, 10) // Пул из 10 горутин
+	walkOpts, archives := opts.walkOptions()
+	defer archives.close()
 
-	errWalk := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+	errWalk := walk(ctx, target, walkOpts, func(e walkEntry, err error) error {
 		if err != nil {
-			return err
+			return errs.add(e.path, "walk", err)
 		}
-		relPath, _ := filepath.Rel(root, path)
-		if info.IsDir() {
-			if utils.IsIgnored(relPath, ignoreList, true) {
-				return filepath.SkipDir
-			}
-			return nil
+		if errs.failed() {
+			return errs.err()
 		}
-		if utils.IsIgnored(relPath, ignoreList, false) {
+		if e.info.IsDir() {
 			return nil
 		}
-		lang := getLanguage(path, ignoredLangs)
+		lang := getLanguage(e.path, ignoredLangs)
 		if lang == "" {
 			return nil
 		}
@@ -64,523 +175,734 @@ func CountCodeLines(root string, ignoreList []string, ignoreLanguages []string)
 		go func() {
 			defer wg.Done()
 			defer func() { <-semaphore }()
-			total, comments, err := analyzeFile(path, lang)
+			file, err := analyzeFile(e.fsys, e.name, e.rel, lang, opts)
 			if err != nil {
-				mu.Lock()
-				errors = append(errors, err)
-				mu.Unlock()
+				errs.add(e.path, "read", err)
 				return
 			}
-			stats.mu.Lock()
-			defer stats.mu.Unlock()
-			if _, exists := stats.Languages[lang]; !exists {
-				stats.Languages[lang] = &LanguageStat{}
-			}
-			stats.Languages[lang].TotalLines += total
-			stats.Languages[lang].CommentLines += comments
-			stats.Languages[lang].CodeLines = stats.Languages[lang].TotalLines - stats.Languages[lang].CommentLines
+			opts.Progress.AddFiles(1)
+			opts.Progress.AddBytes(e.info.Size())
+			stats.add(e.rel, file.withoutLanguages(ignoredLangs), opts.countsClass(file.Class))
 		}()
 		return nil
 	})
 
 	wg.Wait()
-	if errWalk != nil {
+	if errWalk != nil && ctx.Err() == nil {
 		return nil, errWalk
 	}
-
-	mu.Lock()
-	defer mu.Unlock()
-	if len(errors) > 0 {
-		return nil, errors[0]
+	if errs.failed() {
+		return nil, errs.err()
 	}
-	return stats, nil
+	return stats, errs.finish(ctx)
+}
+
+// extensionLanguages сопоставляет расширения файлов языкам.
+var extensionLanguages = map[string]string{
+	".html":     "HTML",
+	".htm":      "HTML",
+	".vue":      "Vue",
+	".svelte":   "Svelte",
+	".css":      "CSS",
+	".js":       "JavaScript",
+	".mjs":      "JavaScript",
+	".cjs":      "JavaScript",
+	".ts":       "TypeScript",
+	".tsx":      "TypeScript",
+	".jsx":      "JavaScript",
+	".go":       "Go",
+	".py":       "Python",
+	".pyw":      "Python",
+	".rb":       "Ruby",
+	".java":     "Java",
+	".cpp":      "C++",
+	".cc":       "C++",
+	".cxx":      "C++",
+	".hpp":      "C++",
+	".h":        "C",
+	".c":        "C",
+	".php":      "PHP",
+	".swift":    "Swift",
+	".kt":       "Kotlin",
+	".kts":      "Kotlin",
+	".rs":       "Rust",
+	".dart":     "Dart",
+	".sh":       "Shell",
+	".bash":     "Shell",
+	".zsh":      "Shell",
+	".pl":       "Perl",
+	".pm":       "Perl",
+	".lua":      "Lua",
+	".sql":      "SQL",
+	".cs":       "C#",
+	".vb":       "Visual Basic",
+	".fs":       "F#",
+	".scala":    "Scala",
+	".hs":       "Haskell",
+	".lhs":      "Haskell",
+	".ml":       "OCaml",
+	".mli":      "OCaml",
+	".pas":      "Pascal",
+	".pp":       "Pascal",
+	".json":     "JSON",
+	".xml":      "XML",
+	".yaml":     "YAML",
+	".yml":      "YAML",
+	".toml":     "TOML",
+	".md":       "Markdown",
+	".markdown": "Markdown",
+	".ipynb":    "Jupyter Notebook",
 }
 
 func getLanguage(path string, ignoreLanguages map[string]bool) string {
-	ext := strings.ToLower(filepath.Ext(path))
-	extToLang := map[string]string{
-		".html":  "HTML",
-		".htm":   "HTML",
-		".css":   "CSS",
-		".js":    "JavaScript",
-		".mjs":   "JavaScript",
-		".cjs":   "JavaScript",
-		".ts":    "TypeScript",
-		".tsx":   "TypeScript",
-		".jsx":   "JavaScript",
-		".go":    "Go",
-		".py":    "Python",
-		".pyw":   "Python",
-		".rb":    "Ruby",
-		".java":  "Java",
-		".cpp":   "C++",
-		".cc":    "C++",
-		".cxx":   "C++",
-		".hpp":   "C++",
-		".h":     "C",
-		".c":     "C",
-		".php":   "PHP",
-		".swift": "Swift",
-		".kt":    "Kotlin",
-		".kts":   "Kotlin",
-		".rs":    "Rust",
-		".dart":  "Dart",
-		".sh":    "Shell",
-		".bash":  "Shell",
-		".zsh":   "Shell",
-		".pl":    "Perl",
-		".pm":    "Perl",
-		".lua":   "Lua",
-		".sql":   "SQL",
-		".cs":    "C#",
-		".vb":    "Visual Basic",
-		".fs":    "F#",
-		".scala": "Scala",
-		".hs":    "Haskell",
-		".lhs":   "Haskell",
-		".ml":    "OCaml",
-		".mli":   "OCaml",
-		".pas":   "Pascal",
-		".pp":    "Pascal",
-		".json":  "JSON",
-		".xml":   "XML",
-		".yaml":  "YAML",
-		".yml":   "YAML",
-		".toml":  "TOML",
-	}
-	lang := extToLang[ext]
+	lang := extensionLanguages[strings.ToLower(filepath.Ext(path))]
 	if lang == "" {
 		return ""
 	}
 	if ignoreLanguages[strings.ToLower(lang)] {
 		return ""
 	}
-	return extToLang[ext]
+	return lang
 }
 
-func analyzeFile(path, lang string) (int, int, error) {
-	file, err := os.Open(path)
+// analyzeFile разбирает файл name из fsys; rel — его путь от корня сканирования.
+func analyzeFile(fsys fs.FS, name, rel, lang string, opts ScanOptions) (FileStat, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return FileStat{}, err
+	}
+	defer f.Close()
+
+	file, generated, err := analyzeSource(f, lang, opts.Complexity)
+	if err != nil {
+		return FileStat{}, err
+	}
+	file.Class = classifyFile(rel, generated)
+	return file, nil
+}
+
+// analyzeSource возвращает статистику файла без класса (он зависит от пути)
+// и признак сгенерированного содержимого. При complexity сложность оценивается
+// по строкам кода (см. estimator), а для Go файл читается целиком и функции
+// разбираются go/parser. Встроенные языки разбираются отдельно (см. analyzeEmbedded).
+func analyzeSource(r io.Reader, lang string, complexity bool) (FileStat, bool, error) {
+	if embedsLanguages(lang) {
+		return analyzeEmbedded(r, lang, complexity)
+	}
+	return analyzePlain(r, lang, complexity)
+}
+
+// analyzePlain разбирает файл одним парсером языка lang.
+func analyzePlain(r io.Reader, lang string, complexity bool) (FileStat, bool, error) {
+	if !complexity {
+		lines, generated, err := analyzeReader(r, lang, lineHook{})
+		return FileStat{Language: lang, LanguageStat: lines}, generated, err
+	}
+
+	var src []byte
+	if lang == "Go" {
+		var err error
+		if src, err = io.ReadAll(r); err != nil {
+			return FileStat{}, false, err
+		}
+		r = bytes.NewReader(src)
+	}
+	var hook lineHook
+	est := newEstimator(lang)
+	if est != nil {
+		hook.onCode = est.line
+	}
+	lines, generated, err := analyzeReader(r, lang, hook)
 	if err != nil {
-		return 0, 0, err
+		return FileStat{}, false, err
 	}
-	defer file.Close()
+	est.finish(&lines)
 
-	reader := bufio.NewReaderSize(file, maxScanTokenSize)
+	file := FileStat{Language: lang, LanguageStat: lines}
+	if lang == "Go" {
+		// Точные метрики вместо оценки; гистограмма отступов остаётся.
+		file.FunctionStats = goFunctions(src)
+		summarizeFunctions(&file.LanguageStat, file.FunctionStats)
+	}
+	return file, generated, nil
+}
+
+// analyzeReader разбирает содержимое файла языка lang и сообщает, выглядит ли
+// оно сгенерированным (пометка в заголовке или минификация). Для языков без
+// парсера возвращается нулевая статистика.
+func analyzeReader(r io.Reader, lang string, hook lineHook) (LanguageStat, bool, error) {
+	parser := lineParser(lang, hook)
+	if parser == nil {
+		return LanguageStat{}, false, nil
+	}
+
+	counter := &countingReader{r: r}
+	reader := bufio.NewReaderSize(counter, maxScanTokenSize)
+	// Peek возвращает срез буфера, поэтому проверить его нужно до разбора.
+	head, _ := reader.Peek(generatedHeaderSize)
+	generated := hasGeneratedHeader(head)
+
+	total, comments, blank, err := parser.Parse(reader)
+	if err != nil {
+		return LanguageStat{}, false, err
+	}
+	lines := LanguageStat{
+		TotalLines:   total,
+		CommentLines: comments,
+		BlankLines:   blank,
+		CodeLines:    total - comments - blank,
+	}
+	return lines, generated || isMinified(lang, counter.n, total), nil
+}
+
+// lineParser возвращает парсер для языка lang, который передаёт строки в hook.
+func lineParser(lang string, hook lineHook) LineParser {
 	var parser LineParser
 	switch lang {
-	case "HTML":
-		parser = &htmlParser{}
+	case "HTML", "Vue", "Svelte":
+		parser = &htmlParser{hook}
+	case "Markdown":
+		parser = &markdownParser{hook}
 	case "CSS":
-		parser = &cssParser{}
+		parser = &cssParser{hook}
 	case "JavaScript", "TypeScript", "Java", "C++", "C", "PHP", "Swift", "Kotlin", "Rust", "Dart", "C#", "Scala", "Go":
-		parser = &cStyleParser{}
+		parser = &cStyleParser{hook}
 	case "Python", "Shell", "Perl", "YAML":
-		parser = &hashParser{}
+		parser = &hashParser{hook}
 	case "Ruby":
-		parser = &rubyParser{}
+		parser = &rubyParser{hook}
 	case "Haskell":
-		parser = &haskellParser{}
+		parser = &haskellParser{hook}
 	case "SQL":
-		parser = &sqlParser{}
+		parser = &sqlParser{hook}
 	case "Lua":
-		parser = &luaParser{}
+		parser = &luaParser{hook}
 	case "Pascal":
-		parser = &pascalParser{}
+		parser = &pascalParser{hook}
 	default:
-		return 0, 0, nil
+		return nil
 	}
-	return parser.Parse(reader)
+	return parser
 }
 
+// LineParser классифицирует строки файла. Пустые строки внутри многострочного
+// комментария считаются комментарием.
 type LineParser interface {
-	Parse(*bufio.Reader) (total int, comments int, err error)
+	Parse(*bufio.Reader) (total, comments, blank int, err error)
 }
 
-type htmlParser struct{}
+// lineHook передаёт строки наблюдателям: строки кода — для оценки сложности,
+// строки комментариев — для поиска пометок (см. FindTodos). n — номер строки с 1,
+// line — строка в исходном виде (с отступом).
+type lineHook struct {
+	onCode    func(n int, line string)
+	onComment func(n int, line string)
+}
+
+func (h lineHook) code(n int, line string) {
+	if h.onCode != nil {
+		h.onCode(n, line)
+	}
+}
 
-func (p *htmlParser) Parse(reader *bufio.Reader) (int, int, error) {
+func (h lineHook) comment(n int, line string) {
+	if h.onComment != nil {
+		h.onComment(n, line)
+	}
+}
+
+type htmlParser struct{ lineHook }
+
+func (p *htmlParser) Parse(reader *bufio.Reader) (int, int, int, error) {
 	inComment := false
-	total, comments := 0, 0
+	total, comments, blank := 0, 0, 0
 	for {
 		line, isPrefix, err := reader.ReadLine()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return 0, 0, err
+			return 0, 0, 0, err
 		}
 		total++
-		lineStr := strings.TrimSpace(string(line))
+		raw := string(line)
 		if isPrefix {
 			var buf bytes.Buffer
 			buf.Write(line)
 			for isPrefix {
 				line, isPrefix, err = reader.ReadLine()
 				if err != nil {
-					return 0, 0, err
+					return 0, 0, 0, err
 				}
 				buf.Write(line)
 			}
-			lineStr = strings.TrimSpace(buf.String())
+			raw = buf.String()
 		}
+		lineStr := strings.TrimSpace(raw)
 		switch {
 		case inComment:
 			comments++
+			p.comment(total, raw)
 			if strings.Contains(lineStr, "-->") {
 				inComment = false
 			}
+		case lineStr == "":
+			blank++
 		case strings.Contains(lineStr, "<!--"):
 			comments++
+			p.comment(total, raw)
 			if !strings.Contains(lineStr, "-->") {
 				inComment = true
 			}
+		default:
+			p.code(total, raw)
 		}
 	}
-	return total, comments, nil
+	return total, comments, blank, nil
 }
 
-type cssParser struct{}
+type cssParser struct{ lineHook }
 
-func (p *cssParser) Parse(reader *bufio.Reader) (int, int, error) {
+func (p *cssParser) Parse(reader *bufio.Reader) (int, int, int, error) {
 	inComment := false
-	total, comments := 0, 0
+	total, comments, blank := 0, 0, 0
 	for {
 		line, isPrefix, err := reader.ReadLine()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return 0, 0, err
+			return 0, 0, 0, err
 		}
 		total++
-		lineStr := strings.TrimSpace(string(line))
+		raw := string(line)
 		if isPrefix {
 			var buf bytes.Buffer
 			buf.Write(line)
 			for isPrefix {
 				line, isPrefix, err = reader.ReadLine()
 				if err != nil {
-					return 0, 0, err
+					return 0, 0, 0, err
 				}
 				buf.Write(line)
 			}
-			lineStr = strings.TrimSpace(buf.String())
+			raw = buf.String()
 		}
+		lineStr := strings.TrimSpace(raw)
 		switch {
 		case inComment:
 			comments++
+			p.comment(total, raw)
 			if strings.Contains(lineStr, "*/") {
 				inComment = false
 			}
+		case lineStr == "":
+			blank++
 		case strings.HasPrefix(lineStr, "/*"):
 			comments++
+			p.comment(total, raw)
 			if !strings.Contains(lineStr, "*/") {
 				inComment = true
 			}
+		default:
+			p.code(total, raw)
 		}
 	}
-	return total, comments, nil
+	return total, comments, blank, nil
 }
 
-type cStyleParser struct{}
+type cStyleParser struct{ lineHook }
 
-func (p *cStyleParser) Parse(reader *bufio.Reader) (int, int, error) {
+func (p *cStyleParser) Parse(reader *bufio.Reader) (int, int, int, error) {
 	inMultiLine := false
-	total, comments := 0, 0
+	total, comments, blank := 0, 0, 0
 	for {
 		line, isPrefix, err := reader.ReadLine()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return 0, 0, err
+			return 0, 0, 0, err
 		}
 		total++
-		lineStr := strings.TrimSpace(string(line))
+		raw := string(line)
 		if isPrefix {
 			var buf bytes.Buffer
 			buf.Write(line)
 			for isPrefix {
 				line, isPrefix, err = reader.ReadLine()
 				if err != nil {
-					return 0, 0, err
+					return 0, 0, 0, err
 				}
 				buf.Write(line)
 			}
-			lineStr = strings.TrimSpace(buf.String())
+			raw = buf.String()
 		}
+		lineStr := strings.TrimSpace(raw)
 		switch {
 		case inMultiLine:
 			comments++
+			p.comment(total, raw)
 			if strings.Contains(lineStr, "*/") {
 				inMultiLine = false
 			}
+		case lineStr == "":
+			blank++
 		case strings.HasPrefix(lineStr, "//"):
 			comments++
+			p.comment(total, raw)
 		case strings.HasPrefix(lineStr, "/*"):
 			comments++
+			p.comment(total, raw)
 			if !strings.Contains(lineStr, "*/") {
 				inMultiLine = true
 			}
+		default:
+			p.code(total, raw)
 		}
 	}
-	return total, comments, nil
+	return total, comments, blank, nil
 }
 
-type hashParser struct{}
+type hashParser struct{ lineHook }
 
-func (p *hashParser) Parse(reader *bufio.Reader) (int, int, error) {
-	total, comments := 0, 0
+func (p *hashParser) Parse(reader *bufio.Reader) (int, int, int, error) {
+	total, comments, blank := 0, 0, 0
 	for {
 		line, isPrefix, err := reader.ReadLine()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return 0, 0, err
+			return 0, 0, 0, err
 		}
 		total++
-		lineStr := strings.TrimSpace(string(line))
+		raw := string(line)
 		if isPrefix {
 			var buf bytes.Buffer
 			buf.Write(line)
 			for isPrefix {
 				line, isPrefix, err = reader.ReadLine()
 				if err != nil {
-					return 0, 0, err
+					return 0, 0, 0, err
 				}
 				buf.Write(line)
 			}
-			lineStr = strings.TrimSpace(buf.String())
+			raw = buf.String()
 		}
-		if strings.HasPrefix(lineStr, "#") {
+		lineStr := strings.TrimSpace(raw)
+		switch {
+		case lineStr == "":
+			blank++
+		case strings.HasPrefix(lineStr, "#"):
 			comments++
+			p.comment(total, raw)
+		default:
+			p.code(total, raw)
 		}
 	}
-	return total, comments, nil
+	return total, comments, blank, nil
 }
 
-type rubyParser struct{}
+type rubyParser struct{ lineHook }
 
-func (p *rubyParser) Parse(reader *bufio.Reader) (int, int, error) {
+func (p *rubyParser) Parse(reader *bufio.Reader) (int, int, int, error) {
 	inMultiLine := false
-	total, comments := 0, 0
+	total, comments, blank := 0, 0, 0
 	for {
 		line, isPrefix, err := reader.ReadLine()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return 0, 0, err
+			return 0, 0, 0, err
 		}
 		total++
-		lineStr := strings.TrimSpace(string(line))
+		raw := string(line)
 		if isPrefix {
 			var buf bytes.Buffer
 			buf.Write(line)
 			for isPrefix {
 				line, isPrefix, err = reader.ReadLine()
 				if err != nil {
-					return 0, 0, err
+					return 0, 0, 0, err
 				}
 				buf.Write(line)
 			}
-			lineStr = strings.TrimSpace(buf.String())
+			raw = buf.String()
 		}
+		lineStr := strings.TrimSpace(raw)
 		switch {
 		case inMultiLine:
 			comments++
+			p.comment(total, raw)
 			if strings.HasPrefix(lineStr, "=end") {
 				inMultiLine = false
 			}
+		case lineStr == "":
+			blank++
 		case strings.HasPrefix(lineStr, "=begin"):
 			comments++
+			p.comment(total, raw)
 			inMultiLine = true
 		case strings.HasPrefix(lineStr, "#"):
 			comments++
+			p.comment(total, raw)
+		default:
+			p.code(total, raw)
 		}
 	}
-	return total, comments, nil
+	return total, comments, blank, nil
 }
 
-type haskellParser struct{}
+type haskellParser struct{ lineHook }
 
-func (p *haskellParser) Parse(reader *bufio.Reader) (int, int, error) {
+func (p *haskellParser) Parse(reader *bufio.Reader) (int, int, int, error) {
 	inMultiLine := false
-	total, comments := 0, 0
+	total, comments, blank := 0, 0, 0
 	for {
 		line, isPrefix, err := reader.ReadLine()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return 0, 0, err
+			return 0, 0, 0, err
 		}
 		total++
-		lineStr := strings.TrimSpace(string(line))
+		raw := string(line)
 		if isPrefix {
 			var buf bytes.Buffer
 			buf.Write(line)
 			for isPrefix {
 				line, isPrefix, err = reader.ReadLine()
 				if err != nil {
-					return 0, 0, err
+					return 0, 0, 0, err
 				}
 				buf.Write(line)
 			}
-			lineStr = strings.TrimSpace(buf.String())
+			raw = buf.String()
 		}
+		lineStr := strings.TrimSpace(raw)
 		switch {
 		case inMultiLine:
 			comments++
+			p.comment(total, raw)
 			if strings.Contains(lineStr, "-}") {
 				inMultiLine = false
 			}
+		case lineStr == "":
+			blank++
 		case strings.HasPrefix(lineStr, "--"):
 			comments++
+			p.comment(total, raw)
 		case strings.HasPrefix(lineStr, "{-"):
 			comments++
+			p.comment(total, raw)
 			if !strings.Contains(lineStr, "-}") {
 				inMultiLine = true
 			}
+		default:
+			p.code(total, raw)
 		}
 	}
-	return total, comments, nil
+	return total, comments, blank, nil
 }
 
-type sqlParser struct{}
+type sqlParser struct{ lineHook }
 
-func (p *sqlParser) Parse(reader *bufio.Reader) (int, int, error) {
+func (p *sqlParser) Parse(reader *bufio.Reader) (int, int, int, error) {
 	inMultiLine := false
-	total, comments := 0, 0
+	total, comments, blank := 0, 0, 0
 	for {
 		line, isPrefix, err := reader.ReadLine()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return 0, 0, err
+			return 0, 0, 0, err
 		}
 		total++
-		lineStr := strings.TrimSpace(string(line))
+		raw := string(line)
 		if isPrefix {
 			var buf bytes.Buffer
 			buf.Write(line)
 			for isPrefix {
 				line, isPrefix, err = reader.ReadLine()
 				if err != nil {
-					return 0, 0, err
+					return 0, 0, 0, err
 				}
 				buf.Write(line)
 			}
-			lineStr = strings.TrimSpace(buf.String())
+			raw = buf.String()
 		}
+		lineStr := strings.TrimSpace(raw)
 		switch {
 		case inMultiLine:
 			comments++
+			p.comment(total, raw)
 			if strings.Contains(lineStr, "*/") {
 				inMultiLine = false
 			}
+		case lineStr == "":
+			blank++
 		case strings.HasPrefix(lineStr, "--"):
 			comments++
+			p.comment(total, raw)
 		case strings.HasPrefix(lineStr, "/*"):
 			comments++
+			p.comment(total, raw)
 			if !strings.Contains(lineStr, "*/") {
 				inMultiLine = true
 			}
+		default:
+			p.code(total, raw)
 		}
 	}
-	return total, comments, nil
+	return total, comments, blank, nil
 }
 
-type luaParser struct{}
+type luaParser struct{ lineHook }
 
-func (p *luaParser) Parse(reader *bufio.Reader) (int, int, error) {
+func (p *luaParser) Parse(reader *bufio.Reader) (int, int, int, error) {
 	inMultiLine := false
-	total, comments := 0, 0
+	total, comments, blank := 0, 0, 0
 	for {
 		line, isPrefix, err := reader.ReadLine()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return 0, 0, err
+			return 0, 0, 0, err
 		}
 		total++
-		lineStr := strings.TrimSpace(string(line))
+		raw := string(line)
 		if isPrefix {
 			var buf bytes.Buffer
 			buf.Write(line)
 			for isPrefix {
 				line, isPrefix, err = reader.ReadLine()
 				if err != nil {
-					return 0, 0, err
+					return 0, 0, 0, err
 				}
 				buf.Write(line)
 			}
-			lineStr = strings.TrimSpace(buf.String())
+			raw = buf.String()
 		}
+		lineStr := strings.TrimSpace(raw)
 		switch {
 		case inMultiLine:
 			comments++
+			p.comment(total, raw)
 			if strings.Contains(lineStr, "]]") {
 				inMultiLine = false
 			}
+		case lineStr == "":
+			blank++
 		case strings.HasPrefix(lineStr, "--"):
 			if strings.HasPrefix(lineStr, "--[[") {
 				comments++
+				p.comment(total, raw)
 				if !strings.Contains(lineStr, "]]") {
 					inMultiLine = true
 				}
 			} else {
 				comments++
+				p.comment(total, raw)
 			}
+		default:
+			p.code(total, raw)
 		}
 	}
-	return total, comments, nil
+	return total, comments, blank, nil
 }
 
-type pascalParser struct{}
+type pascalParser struct{ lineHook }
 
-func (p *pascalParser) Parse(reader *bufio.Reader) (int, int, error) {
+func (p *pascalParser) Parse(reader *bufio.Reader) (int, int, int, error) {
 	inMultiLine := false
-	total, comments := 0, 0
+	total, comments, blank := 0, 0, 0
 	for {
 		line, isPrefix, err := reader.ReadLine()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return 0, 0, err
+			return 0, 0, 0, err
 		}
 		total++
-		lineStr := strings.TrimSpace(string(line))
+		raw := string(line)
 		if isPrefix {
 			var buf bytes.Buffer
 			buf.Write(line)
 			for isPrefix {
 				line, isPrefix, err = reader.ReadLine()
 				if err != nil {
-					return 0, 0, err
+					return 0, 0, 0, err
 				}
 				buf.Write(line)
 			}
-			lineStr = strings.TrimSpace(buf.String())
+			raw = buf.String()
 		}
+		lineStr := strings.TrimSpace(raw)
 		switch {
 		case inMultiLine:
 			comments++
+			p.comment(total, raw)
 			if strings.Contains(lineStr, "}") {
 				inMultiLine = false
 			}
+		case lineStr == "":
+			blank++
 		case strings.HasPrefix(lineStr, "//"):
 			comments++
+			p.comment(total, raw)
 		case strings.HasPrefix(lineStr, "{"):
 			comments++
+			p.comment(total, raw)
 			if !strings.Contains(lineStr, "}") {
 				inMultiLine = true
 			}
+		default:
+			p.code(total, raw)
+		}
+	}
+	return total, comments, blank, nil
+}
+
+// markdownParser считает текст документации комментариями, а не кодом.
+// Блоки кода выделяются в отдельные части до разбора (см. splitSource).
+type markdownParser struct{ lineHook }
+
+func (p *markdownParser) Parse(reader *bufio.Reader) (int, int, int, error) {
+	total, comments, blank := 0, 0, 0
+	for {
+		line, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return 0, 0, 0, err
+		}
+		total++
+		raw := string(line)
+		if isPrefix {
+			var buf bytes.Buffer
+			buf.Write(line)
+			for isPrefix {
+				line, isPrefix, err = reader.ReadLine()
+				if err != nil {
+					return 0, 0, 0, err
+				}
+				buf.Write(line)
+			}
+			raw = buf.String()
+		}
+		if strings.TrimSpace(raw) == "" {
+			blank++
+			continue
 		}
+		comments++
+		p.comment(total, raw)
 	}
-	return total, comments, nil
+	return total, comments, blank, nil
 }
@@ -0,0 +1,211 @@
+package filesystem
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+type lineCounts struct {
+	total, code, comments, blank int
+}
+
+func counts(s LanguageStat) lineCounts {
+	return lineCounts{s.TotalLines, s.CodeLines, s.CommentLines, s.BlankLines}
+}
+
+func TestCountCodeLinesFSParsers(t *testing.T) {
+	tests := []struct {
+		name string
+		lang string
+		src  string
+		want lineCounts
+	}{
+		{
+			name: "main.go",
+			lang: "Go",
+			src:  "// Package main.\npackage main\n\n/* block\n   comment */\nfunc main() {} // trailing\n",
+			want: lineCounts{total: 6, code: 2, comments: 3, blank: 1},
+		},
+		{
+			name: "app.py",
+			lang: "Python",
+			src:  "#!/usr/bin/env python\nimport os\n\n# comment\nprint(os.name)\n",
+			want: lineCounts{total: 5, code: 2, comments: 2, blank: 1},
+		},
+		{
+			name: "query.sql",
+			lang: "SQL",
+			src:  "-- users\nSELECT *\n/* all\n   rows */\nFROM users;\n",
+			want: lineCounts{total: 5, code: 2, comments: 3},
+		},
+		{
+			name: "init.lua",
+			lang: "Lua",
+			src:  "--[[ block\ncomment ]]\nlocal x = 1\n-- line\n",
+			want: lineCounts{total: 4, code: 1, comments: 3},
+		},
+		{
+			name: "Main.hs",
+			lang: "Haskell",
+			src:  "{- block\n-}\nmain = print 1\n\n-- line\n",
+			want: lineCounts{total: 5, code: 1, comments: 3, blank: 1},
+		},
+		{
+			name: "style.css",
+			lang: "CSS",
+			src:  "/* reset */\nbody {\n  margin: 0;\n}\n",
+			want: lineCounts{total: 4, code: 3, comments: 1},
+		},
+		{
+			name: "app.rb",
+			lang: "Ruby",
+			src:  "=begin\ndocs\n=end\nputs 1\n# done\n",
+			want: lineCounts{total: 5, code: 1, comments: 4},
+		},
+	}
+
+	fsys := fstest.MapFS{}
+	for _, tt := range tests {
+		fsys[tt.name] = &fstest.MapFile{Data: []byte(tt.src)}
+	}
+	stats, err := CountCodeLinesFS(context.Background(), fsys, nil, ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, ok := stats.Files[tt.name]
+			if !ok {
+				t.Fatalf("%s not counted", tt.name)
+			}
+			if file.Language != tt.lang {
+				t.Errorf("language %q, want %q", file.Language, tt.lang)
+			}
+			if got := counts(file.LanguageStat); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountCodeLinesFSEmbedded(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("<!-- page -->\n<html>\n<script>\nlet x = 1;\n// note\n</script>\n<style>\nbody {}\n</style>\n</html>\n")},
+		"README.md":  {Data: []byte("# Title\n\nText.\n\n```go\npackage main\n```\n")},
+	}
+	stats, err := CountCodeLinesFS(context.Background(), fsys, nil, ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]lineCounts{
+		"HTML":       {total: 7, code: 6, comments: 1},
+		"JavaScript": {total: 2, code: 1, comments: 1},
+		"CSS":        {total: 1, code: 1},
+		"Markdown":   {total: 6, comments: 4, blank: 2},
+		"Go":         {total: 1, code: 1},
+	}
+	for lang, w := range want {
+		stat, ok := stats.Languages[lang]
+		if !ok {
+			t.Errorf("%s: not counted", lang)
+			continue
+		}
+		if got := counts(*stat); got != w {
+			t.Errorf("%s: got %+v, want %+v", lang, got, w)
+		}
+	}
+	if got := counts(stats.Files["index.html"].LanguageStat); got != want["HTML"] {
+		t.Errorf("index.html counts its markup only: got %+v", got)
+	}
+}
+
+func TestDiffCodeStatsEmbedded(t *testing.T) {
+	count := func(files map[string]string) *CodeStats {
+		t.Helper()
+		fsys := fstest.MapFS{}
+		for name, src := range files {
+			fsys[name] = &fstest.MapFile{Data: []byte(src)}
+		}
+		stats, err := CountCodeLinesFS(context.Background(), fsys, nil, ScanOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stats
+	}
+	before := count(map[string]string{
+		"index.html": "<html>\n<script>\nlet x = 1;\n</script>\n</html>\n",
+		"old.md":     "# Old\n\n```go\npackage old\n```\n",
+	})
+	// В index.html правка только внутри <script>; old.md удалён вместе
+	// с блоком кода на Go.
+	after := count(map[string]string{
+		"index.html": "<html>\n<script>\nlet x = 1;\nlet y = 2;\nlet z = 3;\n</script>\n</html>\n",
+	})
+
+	diff := DiffCodeStats(before, after)
+	type change struct {
+		path, lang        string
+		delta             int
+		embedded, removed bool
+	}
+	var got []change
+	for _, f := range diff.Files {
+		got = append(got, change{f.Path, f.Language, f.Delta().CodeLines, f.Embedded, f.Removed})
+	}
+	want := []change{
+		{"index.html", "JavaScript", 2, true, false},
+		{"old.md", "Go", -1, true, true},
+		{"old.md", "Markdown", 0, false, true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("files: got %+v, want %+v", got, want)
+	}
+
+	// Изменения по файлам складываются в изменения по языкам.
+	byLang := make(map[string]int)
+	for _, f := range diff.Files {
+		byLang[f.Language] += f.Delta().CodeLines
+	}
+	for _, d := range diff.Languages {
+		if byLang[d.Language] != d.Delta().CodeLines {
+			t.Errorf("%s: files sum to %+d, language changed by %+d", d.Language, byLang[d.Language], d.Delta().CodeLines)
+		}
+	}
+}
+
+func TestCountCodeLinesFSIgnoreAndClasses(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":                 {Data: []byte("package main\n")},
+		"vendor/lib/lib.go":       {Data: []byte("package lib\n")},
+		"gen.pb.go":               {Data: []byte("// Code generated by protoc-gen-go. DO NOT EDIT.\npackage main\n")},
+		"web/app.js":              {Data: []byte("let a = 1;\n")},
+		"node_modules/x/index.js": {Data: []byte("module.exports = 1;\n")},
+	}
+	stats, err := CountCodeLinesFS(context.Background(), fsys, []string{"javascript"}, ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stats.Languages["JavaScript"]; ok {
+		t.Error("ignored language counted")
+	}
+	if got := stats.Languages["Go"].CodeLines; got != 1 {
+		t.Errorf("Go code lines %d, want 1 (vendored and generated files excluded)", got)
+	}
+	if got := stats.Excluded[ClassVendored]; got == nil || got.Files != 1 {
+		t.Errorf("vendored: got %+v, want 1 file", got)
+	}
+	if got := stats.Excluded[ClassGenerated]; got == nil || got.Files != 1 {
+		t.Errorf("generated: got %+v, want 1 file", got)
+	}
+
+	all, err := CountCodeLinesFS(context.Background(), fsys, nil, ScanOptions{IncludeGenerated: true, IncludeVendored: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := all.Languages["Go"].CodeLines; got != 3 {
+		t.Errorf("with generated and vendored: Go code lines %d, want 3", got)
+	}
+}
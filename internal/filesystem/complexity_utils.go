@@ -0,0 +1,188 @@
+package filesystem
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+)
+
+// FunctionStat — метрики одной функции или метода.
+type FunctionStat struct {
+	// Name — имя функции; у методов с получателем: "(*Server).Serve".
+	Name string
+	// Path — файл функции (путь от корня сканирования со слешами).
+	Path string
+	Line int
+	// Complexity — цикломатическая сложность: 1 плюс число ветвлений
+	// (if, for, case, &&, ||). Замыкания входят в функцию, где объявлены.
+	Complexity int
+	// Nesting — наибольшая глубина вложенности управляющих конструкций.
+	Nesting int
+}
+
+// goFunctions разбирает исходник Go и возвращает метрики его функций.
+// Файл с синтаксическими ошибками разбирается, насколько это возможно.
+// Фрагменту без объявления пакета (блок кода в Markdown) оно добавляется
+// в первую строку, чтобы номера строк не сдвинулись.
+func goFunctions(src []byte) []FunctionStat {
+	fset := token.NewFileSet()
+	file, _ := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+	if file != nil && file.Name.Name == "" {
+		file, _ = parser.ParseFile(fset, "", append([]byte("package snippet; "), src...), parser.SkipObjectResolution)
+	}
+	if file == nil {
+		return nil
+	}
+
+	var functions []FunctionStat
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		stat := &FunctionStat{Name: funcName(fn), Line: fset.Position(fn.Pos()).Line, Complexity: 1}
+		ast.Walk(complexityVisitor{fn: stat}, fn.Body)
+		functions = append(functions, *stat)
+	}
+	return functions
+}
+
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	return "(" + recvName(fn.Recv.List[0].Type) + ")." + fn.Name.Name
+}
+
+func recvName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + recvName(t.X)
+	case *ast.IndexExpr:
+		return recvName(t.X)
+	case *ast.IndexListExpr:
+		return recvName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return "?"
+}
+
+// complexityVisitor считает ветвления функции fn; depth — текущая вложенность.
+type complexityVisitor struct {
+	fn    *FunctionStat
+	depth int
+}
+
+// nested возвращает обходчик для тела управляющей конструкции.
+func (v complexityVisitor) nested() complexityVisitor {
+	inner := complexityVisitor{fn: v.fn, depth: v.depth + 1}
+	v.fn.Nesting = max(v.fn.Nesting, inner.depth)
+	return inner
+}
+
+func (v complexityVisitor) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.IfStmt:
+		v.fn.Complexity++
+		inner := v.nested()
+		if n.Init != nil {
+			ast.Walk(v, n.Init)
+		}
+		ast.Walk(v, n.Cond)
+		ast.Walk(inner, n.Body)
+		if elseIf, ok := n.Else.(*ast.IfStmt); ok {
+			// else if — продолжение той же цепочки, а не вложенный if.
+			ast.Walk(v, elseIf)
+		} else if n.Else != nil {
+			ast.Walk(inner, n.Else)
+		}
+		return nil
+	case *ast.ForStmt, *ast.RangeStmt:
+		v.fn.Complexity++
+		return v.nested()
+	case *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+		return v.nested()
+	case *ast.CaseClause:
+		if n.List != nil {
+			v.fn.Complexity++
+		}
+	case *ast.CommClause:
+		if n.Comm != nil {
+			v.fn.Complexity++
+		}
+	case *ast.BinaryExpr:
+		if n.Op == token.LAND || n.Op == token.LOR {
+			v.fn.Complexity++
+		}
+	}
+	return v
+}
+
+// summarizeFunctions заменяет оценку сложности файла метриками его функций.
+func summarizeFunctions(stat *LanguageStat, functions []FunctionStat) {
+	stat.Functions, stat.Branches, stat.Complexity, stat.MaxNesting = 0, 0, 0, 0
+	for _, fn := range functions {
+		stat.Functions++
+		stat.Branches += fn.Complexity - 1
+		stat.Complexity += fn.Complexity
+		stat.MaxComplexity = max(stat.MaxComplexity, fn.Complexity)
+		stat.MaxNesting = max(stat.MaxNesting, fn.Nesting)
+	}
+}
+
+// TopFunctions возвращает n самых сложных функций по всем файлам.
+func (s *CodeStats) TopFunctions(n int) []FunctionStat {
+	if n <= 0 {
+		return nil
+	}
+	var functions []FunctionStat
+	for _, file := range s.Files {
+		functions = append(functions, file.FunctionStats...)
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		a, b := functions[i], functions[j]
+		if a.Complexity != b.Complexity {
+			return a.Complexity > b.Complexity
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+	if len(functions) > n {
+		functions = functions[:n]
+	}
+	return functions
+}
+
+// FileComplexity — файл с его оценкой сложности.
+type FileComplexity struct {
+	Path string
+	*FileStat
+}
+
+// TopFiles возвращает n файлов с наибольшей сложностью (Complexity) по всем
+// языкам: так сравниваются горячие точки в многоязычном репозитории.
+func (s *CodeStats) TopFiles(n int) []FileComplexity {
+	if n <= 0 {
+		return nil
+	}
+	var files []FileComplexity
+	for rel, file := range s.Files {
+		if file.Complexity > 0 {
+			files = append(files, FileComplexity{Path: rel, FileStat: file})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Complexity != files[j].Complexity {
+			return files[i].Complexity > files[j].Complexity
+		}
+		return files[i].Path < files[j].Path
+	})
+	if len(files) > n {
+		files = files[:n]
+	}
+	return files
+}
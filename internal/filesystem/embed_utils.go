@@ -0,0 +1,393 @@
+package filesystem
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// embedsLanguages сообщает, содержат ли файлы языка lang фрагменты на других
+// языках: <script> и <style> в HTML, Vue и Svelte, блоки кода в Markdown,
+// ячейки в Jupyter Notebook.
+func embedsLanguages(lang string) bool {
+	switch lang {
+	case "HTML", "Vue", "Svelte", "Markdown", "Jupyter Notebook":
+		return true
+	}
+	return false
+}
+
+// sourcePart — строки файла на одном языке. Первая часть файла — основной
+// язык (разметка или текст), остальные — встроенные фрагменты.
+type sourcePart struct {
+	lang  string
+	lines []string
+	// nums — номера строк части в исходном файле (с 1).
+	nums []int
+}
+
+func (p *sourcePart) add(n int, line string) {
+	p.lines = append(p.lines, line)
+	p.nums = append(p.nums, n)
+}
+
+func (p *sourcePart) reader() io.Reader {
+	return strings.NewReader(strings.Join(p.lines, "\n"))
+}
+
+// hook переводит номера строк части в номера строк исходного файла.
+func (p *sourcePart) hook(h lineHook) lineHook {
+	remap := func(fn func(int, string)) func(int, string) {
+		if fn == nil {
+			return nil
+		}
+		return func(n int, line string) { fn(p.nums[n-1], line) }
+	}
+	return lineHook{onCode: remap(h.onCode), onComment: remap(h.onComment)}
+}
+
+// splitSource читает файл языка lang (см. embedsLanguages) и делит его на части
+// по языкам. Возвращает и признак сгенерированного содержимого.
+func splitSource(r io.Reader, lang string) ([]*sourcePart, bool, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, false, err
+	}
+	generated := hasGeneratedHeader(data[:min(len(data), generatedHeaderSize)])
+
+	if lang == "Jupyter Notebook" {
+		parts, err := splitNotebook(data)
+		return parts, generated, err
+	}
+
+	text := strings.TrimSuffix(string(data), "\n")
+	var lines []string
+	var nums []int
+	if text != "" {
+		for i, line := range strings.Split(text, "\n") {
+			lines = append(lines, strings.TrimSuffix(line, "\r"))
+			nums = append(nums, i+1)
+		}
+	}
+	if lang == "Markdown" {
+		return splitMarkdown(lines, nums), generated, nil
+	}
+	return splitMarkup(lang, lines), generated, nil
+}
+
+var (
+	// embeddedTag — открывающий тег <script> или <style>.
+	embeddedTag = regexp.MustCompile(`(?i)<(script|style)\b([^>]*)>`)
+	// tagAttribute — атрибуты lang и type, которые задают язык блока.
+	tagAttribute = regexp.MustCompile(`(?i)\b(lang|type)\s*=\s*["']?([^"'\s>]+)`)
+)
+
+// splitMarkup выделяет содержимое блоков <script> и <style>. Строки с самими
+// тегами и однострочные блоки остаются в разметке.
+func splitMarkup(host string, lines []string) []*sourcePart {
+	markup := &sourcePart{lang: host}
+	parts := []*sourcePart{markup}
+	var (
+		block   *sourcePart
+		closing string
+	)
+	for i, line := range lines {
+		n := i + 1
+		if block != nil {
+			if strings.Contains(strings.ToLower(line), closing) {
+				block = nil
+				markup.add(n, line)
+			} else {
+				block.add(n, line)
+			}
+			continue
+		}
+		markup.add(n, line)
+
+		tags := embeddedTag.FindAllStringSubmatchIndex(line, -1)
+		if len(tags) == 0 {
+			continue
+		}
+		last := tags[len(tags)-1]
+		tag := strings.ToLower(line[last[2]:last[3]])
+		closing = "</" + tag
+		if strings.Contains(strings.ToLower(line[last[1]:]), closing) {
+			continue
+		}
+		if lang := blockLanguage(tag, line[last[4]:last[5]]); lang != "" {
+			block = &sourcePart{lang: lang}
+			parts = append(parts, block)
+		}
+	}
+	return parts
+}
+
+// blockLanguage определяет язык блока <script> или <style> по атрибутам lang
+// и type. Пустая строка — блок не код (например, type="text/template"), он
+// считается разметкой.
+func blockLanguage(tag, attrs string) string {
+	value := ""
+	for _, m := range tagAttribute.FindAllStringSubmatch(attrs, -1) {
+		value = strings.ToLower(m[2])
+		if strings.EqualFold(m[1], "lang") {
+			break
+		}
+	}
+	if tag == "style" {
+		switch value {
+		case "", "text/css", "css", "scss", "less", "postcss":
+			return "CSS"
+		}
+		return ""
+	}
+	switch value {
+	case "", "module", "text/javascript", "application/javascript", "js", "jsx", "text/babel":
+		return "JavaScript"
+	case "ts", "tsx", "typescript", "text/typescript":
+		return "TypeScript"
+	}
+	return ""
+}
+
+// markdownFence — начало или конец блока кода: ``` или ~~~ с отступом до трёх пробелов.
+var markdownFence = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})\\s*([^`\\s]*)")
+
+// splitMarkdown выделяет блоки кода с указанным языком. Блоки без языка или
+// на языке без парсера остаются текстом.
+func splitMarkdown(lines []string, nums []int) []*sourcePart {
+	text := &sourcePart{lang: "Markdown"}
+	parts := []*sourcePart{text}
+	var (
+		block *sourcePart
+		fence string
+	)
+	for i, line := range lines {
+		n := nums[i]
+		if fence != "" {
+			if m := markdownFence.FindStringSubmatch(line); m != nil && m[2] == "" &&
+				m[1][0] == fence[0] && len(m[1]) >= len(fence) {
+				fence, block = "", nil
+				text.add(n, line)
+			} else if block != nil {
+				block.add(n, line)
+			} else {
+				text.add(n, line)
+			}
+			continue
+		}
+		text.add(n, line)
+		if m := markdownFence.FindStringSubmatch(line); m != nil {
+			fence = m[1]
+			if lang := fenceLanguage(m[2]); lang != "" {
+				block = &sourcePart{lang: lang}
+				parts = append(parts, block)
+			}
+		}
+	}
+	return parts
+}
+
+// languageAliases — названия языков в Markdown и Jupyter, которые не совпадают
+// ни с именем языка, ни с расширением файла.
+var languageAliases = map[string]string{
+	"golang":     "Go",
+	"csharp":     "C#",
+	"c++":        "C++",
+	"javascript": "JavaScript",
+	"typescript": "TypeScript",
+}
+
+// fenceLanguage определяет язык по имени ("python"), расширению ("py") или
+// псевдониму ("golang"). Возвращает пустую строку для языков без парсера.
+func fenceLanguage(name string) string {
+	name = strings.ToLower(strings.Trim(name, "{}."))
+	if name == "" {
+		return ""
+	}
+	lang := languageAliases[name]
+	if lang == "" {
+		lang = extensionLanguages["."+name]
+	}
+	if lang == "" {
+		for _, known := range extensionLanguages {
+			if strings.ToLower(known) == name {
+				lang = known
+				break
+			}
+		}
+	}
+	if lang == "" || !embedsLanguages(lang) && lineParser(lang, lineHook{}) == nil {
+		return ""
+	}
+	return lang
+}
+
+// notebook — то, что нужно из формата .ipynb.
+type notebook struct {
+	Cells []struct {
+		CellType string          `json:"cell_type"`
+		Source   json.RawMessage `json:"source"`
+	} `json:"cells"`
+	Metadata struct {
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+	} `json:"metadata"`
+}
+
+// splitNotebook делит ячейки блокнота: код — на языке ядра (по умолчанию
+// Python), текстовые ячейки — Markdown со своими блоками кода. Строки
+// нумеруются подряд по всем ячейкам.
+func splitNotebook(data []byte) ([]*sourcePart, error) {
+	var nb notebook
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return nil, fmt.Errorf("invalid notebook: %w", err)
+	}
+	kernel := fenceLanguage(nb.Metadata.LanguageInfo.Name)
+	if kernel == "" {
+		kernel = fenceLanguage(nb.Metadata.Kernelspec.Language)
+	}
+	if kernel == "" && nb.Metadata.LanguageInfo.Name == "" && nb.Metadata.Kernelspec.Language == "" {
+		kernel = "Python"
+	}
+
+	var (
+		textLines []string
+		textNums  []int
+		code      []*sourcePart
+		n         int
+	)
+	for _, cell := range nb.Cells {
+		source, err := cellSource(cell.Source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid notebook: %w", err)
+		}
+		if source == "" {
+			continue
+		}
+		var part *sourcePart
+		if cell.CellType == "code" && kernel != "" {
+			part = &sourcePart{lang: kernel}
+			code = append(code, part)
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(source, "\n"), "\n") {
+			n++
+			if part != nil {
+				part.add(n, line)
+			} else {
+				textLines = append(textLines, line)
+				textNums = append(textNums, n)
+			}
+		}
+	}
+	return append(splitMarkdown(textLines, textNums), code...), nil
+}
+
+// cellSource склеивает исходник ячейки: это строка или массив строк.
+func cellSource(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+	var lines []string
+	if err := json.Unmarshal(raw, &lines); err == nil {
+		return strings.Join(lines, ""), nil
+	}
+	var source string
+	if err := json.Unmarshal(raw, &source); err != nil {
+		return "", err
+	}
+	return source, nil
+}
+
+// analyzeEmbedded разбирает файл со встроенными языками: основная часть
+// попадает в статистику файла, остальные — в FileStat.Embedded по языкам.
+func analyzeEmbedded(r io.Reader, lang string, complexity bool) (FileStat, bool, error) {
+	parts, generated, err := splitSource(r, lang)
+	if err != nil {
+		return FileStat{}, false, err
+	}
+	file, _, err := analyzePlain(parts[0].reader(), parts[0].lang, complexity)
+	if err != nil {
+		return FileStat{}, false, err
+	}
+	for _, part := range parts[1:] {
+		sub, _, err := analyzeSource(part.reader(), part.lang, complexity)
+		if err != nil {
+			return FileStat{}, false, err
+		}
+		file.addEmbedded(sub.Language, sub.LanguageStat)
+		for embedded, stat := range sub.Embedded {
+			file.addEmbedded(embedded, *stat)
+		}
+	}
+	return file, generated, nil
+}
+
+func (f *FileStat) addEmbedded(lang string, stat LanguageStat) {
+	if f.Embedded == nil {
+		f.Embedded = make(map[string]*LanguageStat)
+	}
+	if _, exists := f.Embedded[lang]; !exists {
+		f.Embedded[lang] = &LanguageStat{}
+	}
+	f.Embedded[lang].merge(stat)
+}
+
+// withoutLanguages возвращает файл без встроенных фрагментов на языках ignored
+// (ключи в нижнем регистре). Embedded копируется: FileStat может быть общим.
+func (f FileStat) withoutLanguages(ignored map[string]bool) FileStat {
+	if len(f.Embedded) == 0 || len(ignored) == 0 {
+		return f
+	}
+	embedded := make(map[string]*LanguageStat, len(f.Embedded))
+	for lang, stat := range f.Embedded {
+		if !ignored[strings.ToLower(lang)] {
+			embedded[lang] = stat
+		}
+	}
+	f.Embedded = embedded
+	return f
+}
+
+// scanLines передаёт строки файла обработчикам: для файлов со встроенными
+// языками каждая часть разбирается парсером своего языка, номера строк — из
+// исходного файла. hookFor возвращает обработчик для языка части; false —
+// часть пропускается. Возвращает признак сгенерированного содержимого.
+func scanLines(r io.Reader, lang string, hookFor func(lang string) (lineHook, bool)) (bool, error) {
+	if !embedsLanguages(lang) {
+		hook, ok := hookFor(lang)
+		if !ok {
+			return false, nil
+		}
+		_, generated, err := analyzeReader(r, lang, hook)
+		return generated, err
+	}
+
+	parts, generated, err := splitSource(r, lang)
+	if err != nil {
+		return false, err
+	}
+	for i, part := range parts {
+		remapped := func(lang string) (lineHook, bool) {
+			hook, ok := hookFor(lang)
+			return part.hook(hook), ok
+		}
+		if i > 0 {
+			if _, err := scanLines(part.reader(), part.lang, remapped); err != nil {
+				return false, err
+			}
+			continue
+		}
+		if hook, ok := remapped(part.lang); ok {
+			if _, _, err := analyzeReader(part.reader(), part.lang, hook); err != nil {
+				return false, err
+			}
+		}
+	}
+	return generated, nil
+}
@@ -0,0 +1,103 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+)
+
+// ScanError описывает запись, которую не удалось прочитать при сканировании.
+type ScanError struct {
+	Path string
+	Op   string
+	Err  error
+}
+
+func (e *ScanError) Error() string {
+	return e.Op + " " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+func newScanError(path, op string, err error) *ScanError {
+	var scanErr *ScanError
+	if errors.As(err, &scanErr) {
+		return scanErr
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		op, err = pathErr.Op, pathErr.Err
+	}
+	return &ScanError{Path: path, Op: op, Err: err}
+}
+
+// ScanErrors возвращается вместе с частичными результатами, когда часть
+// записей пропущена. Это предупреждения, а не отказ всей операции.
+type ScanErrors []*ScanError
+
+func (e ScanErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%d entries could not be scanned, first: %v", len(e), e[0])
+}
+
+type errorCollector struct {
+	mu      sync.Mutex
+	strict  bool
+	onError func(*ScanError)
+	errs    ScanErrors
+}
+
+func newErrorCollector(opts ScanOptions) *errorCollector {
+	return &errorCollector{strict: opts.Strict, onError: opts.OnError}
+}
+
+// add запоминает ошибку. В строгом режиме она возвращается, чтобы прервать обход.
+func (c *errorCollector) add(path, op string, err error) error {
+	scanErr := newScanError(path, op, err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errs = append(c.errs, scanErr)
+	if c.onError != nil {
+		c.onError(scanErr)
+	}
+	if c.strict {
+		return scanErr
+	}
+	return nil
+}
+
+// failed сообщает, что в строгом режиме уже была ошибка.
+func (c *errorCollector) failed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.strict && len(c.errs) > 0
+}
+
+// finish формирует итоговую ошибку сканирования: при отмене ctx это ctx.Err()
+// вместе с накопленными предупреждениями, иначе — результат err().
+func (c *errorCollector) finish(ctx context.Context) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return errors.Join(ctxErr, c.err())
+	}
+	return c.err()
+}
+
+// err возвращает первую ошибку в строгом режиме или все накопленные как ScanErrors.
+func (c *errorCollector) err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.errs) == 0 {
+		return nil
+	}
+	if c.strict {
+		return c.errs[0]
+	}
+	return c.errs
+}
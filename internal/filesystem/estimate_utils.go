@@ -0,0 +1,229 @@
+package filesystem
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxIndentLevel — последний уровень гистограммы отступов; более глубокие
+// строки попадают в него же.
+const maxIndentLevel = 8
+
+// tabWidth — ширина табуляции при подсчёте отступа.
+const tabWidth = 4
+
+// estimateRules описывает, как оценивать сложность языка без разбора синтаксиса.
+type estimateRules struct {
+	// branches — ключевые слова ветвлений.
+	branches map[string]bool
+	// logical — && и || тоже считаются ветвлениями.
+	logical bool
+	// functions — шаблоны объявлений функций; каждое совпадение в строке кода
+	// считается функцией. Если в шаблоне есть группа, она должна захватить имя,
+	// и совпадения с ключевыми словами (if (x) {) отбрасываются.
+	functions []*regexp.Regexp
+	// ignoreCase — ключевые слова языка не зависят от регистра.
+	ignoreCase bool
+	// quotes — символы, которыми начинаются строковые литералы.
+	quotes string
+}
+
+func keywords(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
+
+// controlKeywords не могут быть именами функций.
+var controlKeywords = keywords("if", "for", "foreach", "while", "switch", "catch", "return",
+	"else", "new", "sizeof", "when", "match", "using", "lock", "synchronized", "elif")
+
+var (
+	cFamilyBranches = keywords("if", "for", "foreach", "while", "case", "catch", "when", "guard")
+	// cFamilyFunction — объявление с типом результата: "public static int sum(int a, int b) {".
+	cFamilyFunction = regexp.MustCompile(`^(?:[\w\[\]<>,.*&:~?]+\s+)+[*&]*(~?[A-Za-z_]\w*)\s*\([^;]*$`)
+	// jsMethod — метод класса или объекта: "async load(url) {".
+	jsMethod   = regexp.MustCompile(`^(?:(?:async|static|get|set|public|private|protected|readonly)\s+)*([A-Za-z_$][\w$]*)\s*\([^)]*\)\s*(?::\s*[^{]+)?\{\s*$`)
+	jsFunction = regexp.MustCompile(`\bfunction\b|=>`)
+)
+
+var estimates = map[string]*estimateRules{
+	"Python": {
+		branches:  keywords("if", "elif", "for", "while", "except", "case", "and", "or"),
+		functions: []*regexp.Regexp{regexp.MustCompile(`^(?:async\s+)?def\s+\w+`)},
+		quotes:    `"'`,
+	},
+	"Ruby": {
+		branches:  keywords("if", "elsif", "unless", "while", "until", "for", "when", "rescue", "and", "or"),
+		logical:   true,
+		functions: []*regexp.Regexp{regexp.MustCompile(`^def\s+`)},
+		quotes:    `"'`,
+	},
+	"Shell": {
+		branches:  keywords("if", "elif", "for", "while", "until"),
+		logical:   true,
+		functions: []*regexp.Regexp{regexp.MustCompile(`^(?:function\s+[\w-]+|[\w-]+\s*\(\s*\))`)},
+		quotes:    `"'`,
+	},
+	"Perl": {
+		branches:  keywords("if", "elsif", "unless", "for", "foreach", "while", "until", "and", "or"),
+		logical:   true,
+		functions: []*regexp.Regexp{regexp.MustCompile(`^sub\s+\w+`)},
+		quotes:    `"'`,
+	},
+	"Lua": {
+		branches:  keywords("if", "elseif", "for", "while", "until", "and", "or"),
+		functions: []*regexp.Regexp{regexp.MustCompile(`\bfunction\b`)},
+		quotes:    `"'`,
+	},
+	"Haskell": {
+		branches:  keywords("if", "case"),
+		logical:   true,
+		functions: []*regexp.Regexp{regexp.MustCompile(`^[a-z_][\w']*\s*::`)},
+		quotes:    `"`,
+	},
+	"SQL": {
+		branches:   keywords("when", "if", "while"),
+		functions:  []*regexp.Regexp{regexp.MustCompile(`(?i)\bcreate\s+(?:or\s+replace\s+)?(?:function|procedure)\b`)},
+		ignoreCase: true,
+		quotes:     `'`,
+	},
+	"Pascal": {
+		branches:   keywords("if", "for", "while", "repeat", "case", "except"),
+		functions:  []*regexp.Regexp{regexp.MustCompile(`(?i)^(?:procedure|function)\s+`)},
+		ignoreCase: true,
+		quotes:     `'`,
+	},
+	"JavaScript": {branches: cFamilyBranches, logical: true, functions: []*regexp.Regexp{jsFunction, jsMethod}, quotes: "\"'`"},
+	"TypeScript": {branches: cFamilyBranches, logical: true, functions: []*regexp.Regexp{jsFunction, jsMethod}, quotes: "\"'`"},
+	"PHP":        {branches: cFamilyBranches, logical: true, functions: []*regexp.Regexp{regexp.MustCompile(`\bfunction\b`)}, quotes: `"'`},
+	"Kotlin":     {branches: cFamilyBranches, logical: true, functions: []*regexp.Regexp{regexp.MustCompile(`\bfun\s+`)}, quotes: `"'`},
+	"Scala":      {branches: cFamilyBranches, logical: true, functions: []*regexp.Regexp{regexp.MustCompile(`\bdef\s+`)}, quotes: `"'`},
+	"Swift":      {branches: cFamilyBranches, logical: true, functions: []*regexp.Regexp{regexp.MustCompile(`\bfunc\s+`)}, quotes: `"`},
+	// В Rust апостроф начинает и время жизни ('a), поэтому строками считаются только "...".
+	"Rust": {branches: cFamilyBranches, logical: true, functions: []*regexp.Regexp{regexp.MustCompile(`\bfn\s+`)}, quotes: `"`},
+	"Go":   {branches: cFamilyBranches, logical: true, functions: []*regexp.Regexp{regexp.MustCompile(`^func\b`)}, quotes: "\"'`"},
+	"Java": {branches: cFamilyBranches, logical: true, functions: []*regexp.Regexp{cFamilyFunction}, quotes: `"'`},
+	"C":    {branches: cFamilyBranches, logical: true, functions: []*regexp.Regexp{cFamilyFunction}, quotes: `"'`},
+	"C++":  {branches: cFamilyBranches, logical: true, functions: []*regexp.Regexp{cFamilyFunction}, quotes: `"'`},
+	"C#":   {branches: cFamilyBranches, logical: true, functions: []*regexp.Regexp{cFamilyFunction}, quotes: `"'`},
+	"Dart": {branches: cFamilyBranches, logical: true, functions: []*regexp.Regexp{cFamilyFunction}, quotes: `"'`},
+}
+
+// estimator оценивает сложность файла по строкам кода: считает ключевые слова
+// ветвлений, объявления функций и уровни отступов. Это эвристика: ключевые
+// слова в многострочных строковых литералах тоже будут посчитаны.
+type estimator struct {
+	rules    *estimateRules
+	widths   map[int]int
+	branches int
+	funcs    int
+}
+
+// newEstimator возвращает nil для языков, у которых нет правил оценки.
+func newEstimator(lang string) *estimator {
+	rules, ok := estimates[lang]
+	if !ok {
+		return nil
+	}
+	return &estimator{rules: rules, widths: make(map[int]int)}
+}
+
+// line учитывает строку кода в исходном виде (с отступом).
+func (e *estimator) line(_ int, raw string) {
+	width := 0
+	for _, r := range raw {
+		if r == ' ' {
+			width++
+		} else if r == '\t' {
+			width += tabWidth - width%tabWidth
+		} else {
+			break
+		}
+	}
+	e.widths[width]++
+
+	trimmed := strings.TrimSpace(raw)
+	for _, re := range e.rules.functions {
+		for _, m := range re.FindAllStringSubmatch(trimmed, -1) {
+			if len(m) > 1 && controlKeywords[m[1]] {
+				continue
+			}
+			e.funcs++
+		}
+	}
+
+	code := stripStrings(trimmed, e.rules.quotes)
+	if e.rules.ignoreCase {
+		code = strings.ToLower(code)
+	}
+	for _, word := range strings.FieldsFunc(code, func(r rune) bool {
+		return !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		if e.rules.branches[word] {
+			e.branches++
+		}
+	}
+	if e.rules.logical {
+		e.branches += strings.Count(code, "&&") + strings.Count(code, "||")
+	}
+}
+
+// finish записывает оценку в stat. Уровень отступа — ширина, делённая на
+// наименьший ненулевой отступ в файле.
+func (e *estimator) finish(stat *LanguageStat) {
+	if e == nil {
+		return
+	}
+	widths := make([]int, 0, len(e.widths))
+	for w := range e.widths {
+		widths = append(widths, w)
+	}
+	sort.Ints(widths)
+	unit := 1
+	for _, w := range widths {
+		if w > 0 {
+			unit = w
+			break
+		}
+	}
+	for _, w := range widths {
+		level := w / unit
+		stat.MaxNesting = max(stat.MaxNesting, level)
+		stat.Indentation[min(level, maxIndentLevel)] += e.widths[w]
+	}
+
+	stat.Functions = e.funcs
+	stat.Branches = e.branches
+	stat.Complexity = e.funcs + e.branches
+}
+
+// stripStrings убирает из строки кода содержимое однострочных строковых литералов.
+func stripStrings(line, quotes string) string {
+	if quotes == "" || !strings.ContainsAny(line, quotes) {
+		return line
+	}
+	var b strings.Builder
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case quote == 0:
+			if strings.ContainsRune(quotes, r) {
+				quote = r
+			}
+			b.WriteRune(r)
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == quote:
+			quote = 0
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
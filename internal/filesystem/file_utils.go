@@ -1,89 +1,162 @@
 package filesystem
 
 import (
-	"crypto/md5"
+	"context"
 	"fmt"
-	"github.com/SHCDevelops/file-manager/lib/utils"
+	"github.com/SHCDevelops/file-manager/internal/progress"
 	"io"
-	"os"
+	"io/fs"
 	"path/filepath"
+	"runtime"
 	"sync"
 )
 
-func FindDuplicates(dir string, ignoreList []string) ([][]string, error) {
-	hashes := make(map[string][]string)
-	var duplicates [][]string
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-	var errors []error
+// FindDuplicates группирует файлы с одинаковым содержимым. Файлы, которые не
+// удалось прочитать, пропускаются и возвращаются в ScanErrors вместе с результатом.
+// При отмене ctx возвращаются группы из уже посчитанных хешей вместе с ctx.Err().
+//
+// Сначала обход собирает размеры файлов, затем хешируются только файлы,
+// размер которых совпадает хотя бы с одним другим.
+func FindDuplicates(ctx context.Context, dir string, opts ScanOptions) ([][]string, error) {
+	return findDuplicates(ctx, hostTarget(dir), opts)
+}
+
+// FindDuplicatesFS работает как FindDuplicates, но обходит fsys.
+func FindDuplicatesFS(ctx context.Context, fsys fs.FS, opts ScanOptions) ([][]string, error) {
+	return findDuplicates(ctx, fsTarget(fsys), opts)
+}
 
-	walkErr := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+func findDuplicates(ctx context.Context, target scanTarget, opts ScanOptions) ([][]string, error) {
+	if _, err := newHash(opts.HashAlgorithm); err != nil {
+		return nil, err
+	}
+
+	bySize := make(map[int64][]walkEntry)
+	errs := newErrorCollector(opts)
+
+	opts.Progress.SetPhase("scanning", 0)
+	walkOpts, archives := opts.walkOptions()
+	defer archives.close()
+
+	walkErr := walk(ctx, target, walkOpts, func(e walkEntry, err error) error {
 		if err != nil {
-			return err
+			return errs.add(e.path, "walk", err)
 		}
 
-		if info.IsDir() {
-			if utils.IsIgnored(path, ignoreList, true) {
-				return filepath.SkipDir
-			}
+		// Ссылки и специальные файлы не сравниваем: ссылка дублировала бы свою цель.
+		if !e.info.Mode().IsRegular() {
 			return nil
 		}
 
-		if utils.IsIgnored(path, ignoreList, false) {
-			return nil
+		opts.Progress.AddFiles(1)
+		bySize[e.info.Size()] = append(bySize[e.info.Size()], e)
+		return nil
+	})
+
+	if walkErr != nil && ctx.Err() == nil {
+		return nil, walkErr
+	}
+
+	var candidates []walkEntry
+	var totalBytes int64
+	for size, files := range bySize {
+		if len(files) > 1 {
+			candidates = append(candidates, files...)
+			totalBytes += size * int64(len(files))
 		}
+	}
+
+	hashes := make(map[string][]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	entries := make(chan walkEntry)
 
+	opts.Progress.SetPhase("hashing", totalBytes)
+	for i := 0; i < opts.concurrency(runtime.NumCPU()); i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			hash, err := HashFile(path)
-			if err != nil {
+			for e := range entries {
+				hash, err := hashFile(ctx, e.fsys, e.name, opts.HashAlgorithm, opts.Progress)
+				if err != nil {
+					if ctx.Err() == nil {
+						errs.add(e.path, "hash", err)
+					}
+					continue
+				}
+
 				mu.Lock()
-				errors = append(errors, err)
+				hashes[hash] = append(hashes[hash], e.path)
 				mu.Unlock()
-				return
 			}
-
-			mu.Lock()
-			hashes[hash] = append(hashes[hash], path)
-			mu.Unlock()
 		}()
+	}
 
-		return nil
-	})
-
-	wg.Wait()
-
-	if walkErr != nil {
-		return nil, walkErr
+	for _, e := range candidates {
+		if ctx.Err() != nil || errs.failed() {
+			break
+		}
+		entries <- e
 	}
+	close(entries)
+	wg.Wait()
 
-	mu.Lock()
-	defer mu.Unlock()
-	if len(errors) > 0 {
-		return nil, errors[0]
+	if errs.failed() {
+		return nil, errs.err()
 	}
 
+	var duplicates [][]string
 	for _, files := range hashes {
 		if len(files) > 1 {
 			duplicates = append(duplicates, files)
 		}
 	}
 
-	return duplicates, nil
+	return duplicates, errs.finish(ctx)
 }
 
-func HashFile(path string) (string, error) {
-	file, err := os.Open(path)
+// HashFile возвращает хеш содержимого файла в hex; пустой algorithm означает md5.
+func HashFile(ctx context.Context, path string, algorithm HashAlgorithm) (string, error) {
+	return hashFile(ctx, DirFS(filepath.Dir(path)), filepath.Base(path), algorithm, nil)
+}
+
+// HashFileFS работает как HashFile для файла name внутри fsys.
+func HashFileFS(ctx context.Context, fsys fs.FS, name string, algorithm HashAlgorithm) (string, error) {
+	return hashFile(ctx, fsys, name, algorithm, nil)
+}
+
+// hashFile считает хеш и отмечает прочитанные байты в счётчике прогресса.
+func hashFile(ctx context.Context, fsys fs.FS, name string, algorithm HashAlgorithm, counter *progress.Counter) (string, error) {
+	hash, err := newHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := fsys.Open(name)
 	if err != nil {
 		return "", err
 	}
 	defer file.Close()
 
-	hash := md5.New()
-	if _, err := io.Copy(hash, file); err != nil {
+	if _, err := io.Copy(hash, &contextReader{ctx: ctx, r: file, counter: counter}); err != nil {
 		return "", err
 	}
 
 	return fmt.Sprintf("%x", hash.Sum(nil)), nil
 }
+
+// contextReader прерывает чтение при отмене контекста.
+type contextReader struct {
+	ctx     context.Context
+	r       io.Reader
+	counter *progress.Counter
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.r.Read(p)
+	r.counter.AddBytes(int64(n))
+	return n, err
+}
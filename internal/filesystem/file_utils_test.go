@@ -0,0 +1,63 @@
+package filesystem
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFindDuplicatesFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":          {Data: []byte("same content")},
+		"copy/a.txt":     {Data: []byte("same content")},
+		"copy/deep/a.md": {Data: []byte("same content")},
+		// Тот же размер, другое содержимое: отсеивается только хешем.
+		"b.txt":      {Data: []byte("other conten")},
+		"c.txt":      {Data: []byte("unique")},
+		"pair/1":     {Data: []byte("xy")},
+		"pair/2":     {Data: []byte("xy")},
+		"skip/a.txt": {Data: []byte("same content")},
+	}
+
+	for _, algorithm := range []HashAlgorithm{"", HashSHA1, HashSHA256, HashSHA512} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			groups, err := FindDuplicatesFS(context.Background(), fsys, ScanOptions{
+				IgnoreList:    []string{"skip"},
+				HashAlgorithm: algorithm,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, group := range groups {
+				sort.Strings(group)
+			}
+			sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+			want := [][]string{
+				{"a.txt", "copy/a.txt", "copy/deep/a.md"},
+				{"pair/1", "pair/2"},
+			}
+			if !reflect.DeepEqual(groups, want) {
+				t.Errorf("got %q, want %q", groups, want)
+			}
+		})
+	}
+}
+
+func TestFindDuplicatesFSUnknownHash(t *testing.T) {
+	if _, err := FindDuplicatesFS(context.Background(), fstest.MapFS{}, ScanOptions{HashAlgorithm: "crc"}); err == nil {
+		t.Error("expected an error for an unknown hash algorithm")
+	}
+}
+
+func TestHashFileFS(t *testing.T) {
+	fsys := fstest.MapFS{"a.txt": {Data: []byte("abc")}}
+	got, err := HashFileFS(context.Background(), fsys, "a.txt", HashMD5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "900150983cd24fb0d6963f7d28e17f72"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
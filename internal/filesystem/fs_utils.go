@@ -0,0 +1,94 @@
+package filesystem
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// LstatFS — необязательное расширение fs.FS для файловых систем с символическими
+// ссылками. Без него ссылки не отличаются от своих целей.
+type LstatFS interface {
+	fs.FS
+	Lstat(name string) (fs.FileInfo, error)
+}
+
+// DirFS возвращает fs.FS для директории на диске. В отличие от os.DirFS она
+// реализует LstatFS, а ошибки содержат полный путь на диске.
+func DirFS(dir string) fs.FS {
+	return &dirFS{dir: dir}
+}
+
+type dirFS struct {
+	dir string
+}
+
+func (f *dirFS) join(name string, op string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(f.dir, filepath.FromSlash(name)), nil
+}
+
+func (f *dirFS) Open(name string) (fs.File, error) {
+	path, err := f.join(name, "open")
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (f *dirFS) Stat(name string) (fs.FileInfo, error) {
+	path, err := f.join(name, "stat")
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(path)
+}
+
+func (f *dirFS) Lstat(name string) (fs.FileInfo, error) {
+	path, err := f.join(name, "lstat")
+	if err != nil {
+		return nil, err
+	}
+	return os.Lstat(path)
+}
+
+func (f *dirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	path, err := f.join(name, "readdir")
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadDir(path)
+}
+
+func lstat(fsys fs.FS, name string) (fs.FileInfo, error) {
+	if l, ok := fsys.(LstatFS); ok {
+		return l.Lstat(name)
+	}
+	return fs.Stat(fsys, name)
+}
+
+// scanTarget — что сканировать: произвольная fs.FS или директория на диске.
+// Для директории на диске пути в результатах строятся от неё, как раньше
+// возвращал filepath.Walk; для fs.FS это имена внутри неё.
+type scanTarget struct {
+	fsys fs.FS
+	dir  string
+}
+
+func hostTarget(dir string) scanTarget {
+	return scanTarget{fsys: DirFS(dir), dir: dir}
+}
+
+func fsTarget(fsys fs.FS) scanTarget {
+	return scanTarget{fsys: fsys}
+}
+
+// display переводит путь относительно корня сканирования в путь для вывода.
+func (t scanTarget) display(rel string) string {
+	if t.dir == "" {
+		return rel
+	}
+	return filepath.Join(t.dir, filepath.FromSlash(rel))
+}
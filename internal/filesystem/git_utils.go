@@ -0,0 +1,83 @@
+package filesystem
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/SHCDevelops/file-manager/internal/git"
+)
+
+// AuthorStat — строки, последним изменением которых владеет автор.
+type AuthorStat struct {
+	Author    string
+	Lines     int
+	Languages map[string]int
+}
+
+// CountAuthors приписывает строки файлов из stats авторам по данным git blame.
+// dir — директория, для которой посчитан stats. Результат отсортирован по
+// убыванию строк; файлы, для которых blame не удался, возвращаются в ScanErrors.
+func CountAuthors(ctx context.Context, dir string, stats *CodeStats, opts ScanOptions) ([]AuthorStat, error) {
+	authors := make(map[string]*AuthorStat)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := newErrorCollector(opts)
+	semaphore := make(chan struct{}, opts.concurrency(10))
+
+	opts.Progress.SetPhase("blaming", 0)
+	for rel, file := range stats.Files {
+		if strings.Contains(rel, archiveSeparator+"/") {
+			// Файлы внутри архивов git не отслеживает.
+			continue
+		}
+		if ctx.Err() != nil || errs.failed() {
+			break
+		}
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			lines, err := git.Blame(ctx, dir, rel)
+			if err != nil {
+				if ctx.Err() == nil {
+					errs.add(filepath.Join(dir, filepath.FromSlash(rel)), "blame", err)
+				}
+				return
+			}
+			opts.Progress.AddFiles(1)
+
+			mu.Lock()
+			defer mu.Unlock()
+			for author, n := range lines {
+				a, ok := authors[author]
+				if !ok {
+					a = &AuthorStat{Author: author, Languages: make(map[string]int)}
+					authors[author] = a
+				}
+				a.Lines += n
+				a.Languages[file.Language] += n
+			}
+		}()
+	}
+	wg.Wait()
+
+	if errs.failed() {
+		return nil, errs.err()
+	}
+	result := make([]AuthorStat, 0, len(authors))
+	for _, a := range authors {
+		result = append(result, *a)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Lines != result[j].Lines {
+			return result[i].Lines > result[j].Lines
+		}
+		return result[i].Author < result[j].Author
+	})
+	return result, errs.finish(ctx)
+}
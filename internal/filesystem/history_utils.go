@@ -0,0 +1,193 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/SHCDevelops/file-manager/internal/git"
+	"github.com/SHCDevelops/file-manager/lib/utils"
+)
+
+// Revision — статистика кода в дереве одного коммита.
+type Revision struct {
+	Commit    string
+	Date      time.Time
+	Subject   string
+	Languages map[string]*LanguageStat
+}
+
+// CodeStatsHistory считает строки кода, как CountCodeLines, в каждом коммите
+// из log, от старых к новым. dir — директория внутри git-репозитория; учитываются
+// только её файлы. Неизменившиеся файлы не разбираются повторно. Файлы, которые
+// не удалось прочитать, пропускаются и возвращаются в ScanErrors.
+func CodeStatsHistory(ctx context.Context, dir string, log git.LogOptions, ignoreLanguages []string, opts ScanOptions) ([]Revision, error) {
+	commits, err := git.Log(ctx, dir, log)
+	if err != nil {
+		return nil, err
+	}
+	counter, err := newTreeCounter(ctx, dir, ignoreLanguages, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer counter.close()
+
+	revisions := make([]Revision, 0, len(commits))
+	for _, commit := range commits {
+		stats, err := counter.count(ctx, commit.Hash)
+		if err != nil {
+			if ctx.Err() != nil {
+				// Статистика прерванного коммита неполная.
+				break
+			}
+			return nil, err
+		}
+		revisions = append(revisions, Revision{
+			Commit:    commit.Hash,
+			Date:      commit.Date,
+			Subject:   commit.Subject,
+			Languages: stats.Languages,
+		})
+	}
+	return revisions, counter.errs.finish(ctx)
+}
+
+// CountCodeLinesAt работает как CountCodeLines для дерева ревизии rev
+// (коммит, ветка или тег) вместо рабочей копии dir.
+func CountCodeLinesAt(ctx context.Context, dir, rev string, ignoreLanguages []string, opts ScanOptions) (*CodeStats, error) {
+	if strings.HasPrefix(rev, "-") {
+		return nil, fmt.Errorf("invalid revision %q", rev)
+	}
+	counter, err := newTreeCounter(ctx, dir, ignoreLanguages, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer counter.close()
+
+	stats, err := counter.count(ctx, rev)
+	if err != nil && ctx.Err() == nil {
+		return nil, err
+	}
+	return stats, counter.errs.finish(ctx)
+}
+
+// treeCounter считает строки в деревьях коммитов одного репозитория.
+type treeCounter struct {
+	dir          string
+	opts         ScanOptions
+	ignoredLangs map[string]bool
+	blobs        *git.BlobReader
+	// cache: статистика зависит только от содержимого и языка, так что её
+	// можно переиспользовать между коммитами.
+	cache map[blobKey]blobStat
+	errs  *errorCollector
+}
+
+type blobKey struct{ blob, lang string }
+
+type blobStat struct {
+	file      FileStat
+	generated bool
+}
+
+func newTreeCounter(ctx context.Context, dir string, ignoreLanguages []string, opts ScanOptions) (*treeCounter, error) {
+	blobs, err := git.NewBlobReader(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	c := &treeCounter{
+		dir:          dir,
+		opts:         opts,
+		ignoredLangs: make(map[string]bool),
+		blobs:        blobs,
+		cache:        make(map[blobKey]blobStat),
+		errs:         newErrorCollector(opts),
+	}
+	for _, lang := range ignoreLanguages {
+		c.ignoredLangs[strings.ToLower(lang)] = true
+	}
+	opts.Progress.SetPhase("counting", 0)
+	return c, nil
+}
+
+func (c *treeCounter) close() {
+	c.blobs.Close()
+}
+
+// count возвращает статистику дерева rev. Пропущенные файлы попадают в c.errs;
+// ошибка возвращается, только если rev не прочитать целиком, в строгом режиме
+// или при отмене ctx.
+func (c *treeCounter) count(ctx context.Context, rev string) (*CodeStats, error) {
+	entries, err := git.Tree(ctx, c.dir, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := newCodeStats()
+	for _, e := range entries {
+		if !includeTreeEntry(e.Path, c.opts) {
+			continue
+		}
+		lang := getLanguage(e.Path, c.ignoredLangs)
+		if lang == "" {
+			continue
+		}
+
+		key := blobKey{e.Blob, lang}
+		blob, ok := c.cache[key]
+		if !ok {
+			data, err := c.blobs.Read(e.Blob)
+			if err == nil {
+				blob.file, blob.generated, err = analyzeSource(bytes.NewReader(data), lang, c.opts.Complexity)
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				if err := c.errs.add(shortRev(rev)+":"+e.Path, "read", err); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			c.cache[key] = blob
+			c.opts.Progress.AddFiles(1)
+			c.opts.Progress.AddBytes(int64(len(data)))
+		}
+		file := blob.file.withoutLanguages(c.ignoredLangs)
+		file.Class = classifyFile(e.Path, blob.generated)
+		stats.add(e.Path, file, c.opts.countsClass(file.Class))
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// shortRev сокращает хеш коммита для сообщений; имена веток не меняются.
+func shortRev(rev string) string {
+	if len(rev) == 40 && strings.Trim(rev, "0123456789abcdef") == "" {
+		return rev[:12]
+	}
+	return rev
+}
+
+// includeTreeEntry применяет к файлу из дерева коммита те же исключения,
+// что и обход директории: файл пропускается и тогда, когда исключена
+// одна из его родительских директорий.
+func includeTreeEntry(rel string, opts ScanOptions) bool {
+	if opts.Include != nil && !opts.Include(rel, false) {
+		return false
+	}
+	if utils.IsIgnored(rel, opts.IgnoreList, false) {
+		return false
+	}
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		if utils.IsIgnored(dir, opts.IgnoreList, true) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,324 @@
+package filesystem
+
+import (
+	"context"
+	_ "embed"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// maxLicenseFileSize — сколько байт файла LICENSE читается для распознавания.
+const maxLicenseFileSize = 64 * 1024
+
+//go:embed licenses.txt
+var licenseCorpusText string
+
+// licensePattern — фразы, по которым распознаётся лицензия id.
+type licensePattern struct {
+	id      string
+	phrases []string
+}
+
+var licenseCorpus = parseLicenseCorpus(licenseCorpusText)
+
+// parseLicenseCorpus разбирает licenses.txt; фразы сразу нормализуются.
+func parseLicenseCorpus(text string) []licensePattern {
+	var corpus []licensePattern
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "", strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "="):
+			corpus = append(corpus, licensePattern{id: strings.TrimSpace(line[1:])})
+		case len(corpus) > 0:
+			last := &corpus[len(corpus)-1]
+			last.phrases = append(last.phrases, normalizeLicenseText(line))
+		}
+	}
+	return corpus
+}
+
+// normalizeLicenseText приводит текст к словам в нижнем регистре через пробел
+// с пробелами по краям: так фразы сравниваются целыми словами, без учёта
+// переносов строк, символов комментариев и пунктуации.
+func normalizeLicenseText(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return " " + strings.Join(words, " ") + " "
+}
+
+// matchLicenseText возвращает SPDX-идентификатор первой лицензии корпуса,
+// фраза которой встречается в тексте, или пустую строку.
+func matchLicenseText(text string) string {
+	normalized := normalizeLicenseText(text)
+	for _, pattern := range licenseCorpus {
+		for _, phrase := range pattern.phrases {
+			if strings.Contains(normalized, phrase) {
+				return pattern.id
+			}
+		}
+	}
+	return ""
+}
+
+var spdxIdentifier = regexp.MustCompile(`SPDX-License-Identifier:\s*(.*?)\s*(?:\*/|-->|-})?\s*$`)
+
+// detectLicense ищет в тексте тег SPDX-License-Identifier, а без него —
+// фразы из корпуса.
+func detectLicense(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if m := spdxIdentifier.FindStringSubmatch(line); m != nil && m[1] != "" {
+			return m[1]
+		}
+	}
+	return matchLicenseText(text)
+}
+
+// licenseIDs возвращает идентификаторы лицензий из SPDX-выражения
+// ("MIT OR Apache-2.0" → MIT, Apache-2.0) без исключений после WITH.
+func licenseIDs(expr string) []string {
+	fields := strings.FieldsFunc(expr, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '(' || r == ')'
+	})
+	var ids []string
+	for i := 0; i < len(fields); i++ {
+		switch strings.ToUpper(fields[i]) {
+		case "AND", "OR":
+		case "WITH":
+			i++
+		default:
+			ids = append(ids, strings.TrimSuffix(fields[i], "+"))
+		}
+	}
+	return ids
+}
+
+// isLicenseFile сообщает, похоже ли имя на файл с текстом лицензии:
+// LICENSE, LICENCE.md, COPYING.LESSER, LICENSE-MIT и т.п. Документация
+// (LICENSE.md) считается лицензией, файлы исходного кода (license.go) — нет.
+func isLicenseFile(name string, lang string) bool {
+	upper := strings.ToUpper(name)
+	for _, prefix := range []string{"LICENSE", "LICENCE", "COPYING", "UNLICENSE"} {
+		rest, ok := strings.CutPrefix(upper, prefix)
+		if ok && (rest == "" || strings.ContainsRune(".-_", rune(rest[0]))) {
+			return !needsLicenseHeader(lang)
+		}
+	}
+	return false
+}
+
+// needsLicenseHeader сообщает, проверяется ли заголовок с лицензией у файлов
+// языка lang. В форматах без комментариев (JSON) заголовка быть не может,
+// а документацию (Markdown, блокноты) покрывают файлы LICENSE.
+func needsLicenseHeader(lang string) bool {
+	switch lang {
+	case "", "Markdown", "Jupyter Notebook":
+		return false
+	}
+	return embedsLanguages(lang) || lineParser(lang, lineHook{}) != nil
+}
+
+// FileLicense — лицензия файла исходного кода или файла LICENSE.
+type FileLicense struct {
+	Path string
+	// License — SPDX-выражение из заголовка или идентификатор лицензии,
+	// распознанной по тексту; пусто, если лицензия не найдена.
+	License string
+	// Governing — лицензии ближайших файлов LICENSE в директории файла или выше
+	// (через " OR ", если их несколько); пусто, если таких файлов нет.
+	Governing string
+	// Conflict — лицензия в заголовке не совпадает ни с одной из Governing.
+	Conflict bool
+
+	rel string
+}
+
+// LicenseReport — результат FindLicenses.
+type LicenseReport struct {
+	// LicenseFiles — файлы LICENSE, COPYING и т.п.
+	LicenseFiles []FileLicense
+	// Files — файлы исходного кода поддерживаемых языков.
+	Files []FileLicense
+}
+
+// FindLicenses составляет перечень лицензий: распознаёт файлы LICENSE и
+// заголовки файлов исходного кода (первый блок комментариев до кода) по тегу
+// SPDX-License-Identifier или по тексту лицензии. Сгенерированные и сторонние
+// файлы пропускаются, если их не включают ScanOptions.
+func FindLicenses(ctx context.Context, root string, ignoreLanguages []string, opts ScanOptions) (*LicenseReport, error) {
+	return findLicenses(ctx, hostTarget(root), ignoreLanguages, opts)
+}
+
+// FindLicensesFS работает как FindLicenses, но обходит fsys.
+func FindLicensesFS(ctx context.Context, fsys fs.FS, ignoreLanguages []string, opts ScanOptions) (*LicenseReport, error) {
+	return findLicenses(ctx, fsTarget(fsys), ignoreLanguages, opts)
+}
+
+func findLicenses(ctx context.Context, target scanTarget, ignoreLanguages []string, opts ScanOptions) (*LicenseReport, error) {
+	ignoredLangs := make(map[string]bool)
+	for _, lang := range ignoreLanguages {
+		ignoredLangs[strings.ToLower(lang)] = true
+	}
+
+	var (
+		mu     sync.Mutex
+		report LicenseReport
+	)
+	opts.Progress.SetPhase("reading", 0)
+	errs, err := scanFiles(ctx, target, opts, func(e walkEntry) func() error {
+		lang := getLanguage(e.path, ignoredLangs)
+		licenseFile := isLicenseFile(e.info.Name(), lang)
+		if !licenseFile && !needsLicenseHeader(lang) {
+			return nil
+		}
+		return func() error {
+			var (
+				license string
+				counted bool
+				err     error
+			)
+			if licenseFile {
+				license, counted, err = readLicenseFile(e, opts)
+			} else {
+				license, counted, err = readLicenseHeader(e, lang, opts)
+			}
+			if err != nil || !counted {
+				return err
+			}
+			f := FileLicense{Path: e.path, License: license, rel: e.rel}
+			mu.Lock()
+			if licenseFile {
+				report.LicenseFiles = append(report.LicenseFiles, f)
+			} else {
+				report.Files = append(report.Files, f)
+			}
+			mu.Unlock()
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.resolve()
+	return &report, errs.finish(ctx)
+}
+
+// readLicenseFile распознаёт лицензию по началу файла LICENSE.
+func readLicenseFile(e walkEntry, opts ScanOptions) (string, bool, error) {
+	if !opts.countsClass(classifyFile(e.rel, false)) {
+		return "", false, nil
+	}
+	f, err := e.fsys.Open(e.name)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxLicenseFileSize))
+	if err != nil {
+		return "", false, err
+	}
+	return detectLicense(string(data)), true, nil
+}
+
+// readLicenseHeader разбирает файл парсером его языка и распознаёт лицензию
+// по комментариям до первой строки кода.
+func readLicenseHeader(e walkEntry, lang string, opts ScanOptions) (string, bool, error) {
+	f, err := e.fsys.Open(e.name)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+
+	var header strings.Builder
+	inHeader := true
+	hook := lineHook{
+		onCode: func(int, string) { inHeader = false },
+		onComment: func(_ int, line string) {
+			if inHeader {
+				header.WriteString(line)
+				header.WriteByte('\n')
+			}
+		},
+	}
+	// Основная часть файла разбирается первой, поэтому заголовок HTML или Vue
+	// не смешивается с комментариями во встроенных блоках.
+	generated, err := scanLines(f, lang, func(string) (lineHook, bool) { return hook, true })
+	if err != nil {
+		return "", false, err
+	}
+	if !opts.countsClass(classifyFile(e.rel, generated)) {
+		return "", false, nil
+	}
+	return detectLicense(header.String()), true, nil
+}
+
+// resolve сортирует отчёт и сверяет лицензии файлов с ближайшими файлами LICENSE.
+func (r *LicenseReport) resolve() {
+	byPath := func(files []FileLicense) {
+		sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	}
+	byPath(r.LicenseFiles)
+	byPath(r.Files)
+
+	// Директория (путь со слешами) → лицензии её файлов LICENSE.
+	dirs := make(map[string][]string)
+	for _, lf := range r.LicenseFiles {
+		if lf.License != "" {
+			dir := path.Dir(lf.rel)
+			dirs[dir] = append(dirs[dir], lf.License)
+		}
+	}
+	for i := range r.Files {
+		f := &r.Files[i]
+		governing := nearestLicenses(dirs, path.Dir(f.rel))
+		if len(governing) == 0 {
+			continue
+		}
+		f.Governing = strings.Join(governing, " OR ")
+		f.Conflict = f.License != "" && !sharesLicense(f.License, f.Governing)
+	}
+}
+
+// nearestLicenses возвращает лицензии ближайшей к dir директории с файлами LICENSE.
+func nearestLicenses(dirs map[string][]string, dir string) []string {
+	for {
+		if licenses, ok := dirs[dir]; ok {
+			return licenses
+		}
+		parent := path.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+// sharesLicense сообщает, есть ли у двух SPDX-выражений общий идентификатор.
+// Варианты -only и -or-later одной лицензии считаются одинаковыми: по тексту
+// файла LICENSE их не различить.
+func sharesLicense(a, b string) bool {
+	ids := make(map[string]bool)
+	for _, id := range licenseIDs(b) {
+		ids[baseLicenseID(id)] = true
+	}
+	for _, id := range licenseIDs(a) {
+		if ids[baseLicenseID(id)] {
+			return true
+		}
+	}
+	return false
+}
+
+func baseLicenseID(id string) string {
+	id = strings.ToLower(id)
+	id = strings.TrimSuffix(id, "-only")
+	return strings.TrimSuffix(id, "-or-later")
+}
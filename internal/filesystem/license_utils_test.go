@@ -0,0 +1,114 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// TestMatchLicenseTextCorpus распознаёт полные тексты лицензий из
+// testdata/licenses: по файлу <SPDX-идентификатор>.txt на каждый блок корпуса.
+func TestMatchLicenseTextCorpus(t *testing.T) {
+	for _, pattern := range licenseCorpus {
+		t.Run(pattern.id, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "licenses", pattern.id+".txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := matchLicenseText(string(data)); got != pattern.id {
+				t.Errorf("got %q", got)
+			}
+			// Файл LICENSE читается не целиком.
+			if len(data) > maxLicenseFileSize {
+				data = data[:maxLicenseFileSize]
+			}
+			if got := detectLicense(string(data)); got != pattern.id {
+				t.Errorf("first %d bytes: got %q", len(data), got)
+			}
+		})
+	}
+}
+
+func TestMatchLicenseTextHeaders(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{
+			header: `This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.`,
+			want: "AGPL-3.0",
+		},
+		{
+			header: `This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.`,
+			want: "GPL-3.0",
+		},
+		{
+			header: `This library is free software; you can redistribute it and/or
+modify it under the terms of the GNU Lesser General Public
+License as published by the Free Software Foundation; either
+version 2.1 of the License, or (at your option) any later version.`,
+			want: "LGPL-2.1",
+		},
+		{
+			header: `This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.`,
+			want: "MPL-2.0",
+		},
+		{
+			header: `Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.`,
+			want: "Apache-2.0",
+		},
+		{
+			header: "Copyright 2024 The Authors. All rights reserved.",
+			want:   "",
+		},
+	}
+	for _, tt := range tests {
+		if got := matchLicenseText(tt.header); got != tt.want {
+			t.Errorf("%q: got %q, want %q", strings.SplitN(tt.header, "\n", 2)[0], got, tt.want)
+		}
+	}
+}
+
+func TestFindLicensesFSLicenseFileNames(t *testing.T) {
+	gpl, err := os.ReadFile(filepath.Join("testdata", "licenses", "GPL-3.0.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mit, err := os.ReadFile(filepath.Join("testdata", "licenses", "MIT.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"LICENSE.md":     {Data: gpl},
+		"docs/COPYING":   {Data: mit},
+		"license.go":     {Data: []byte("// SPDX-License-Identifier: GPL-3.0\npackage main\n")},
+		"docs/README.md": {Data: []byte("# Docs\n")},
+	}
+	report, err := FindLicensesFS(context.Background(), fsys, nil, ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var licenseFiles []string
+	for _, f := range report.LicenseFiles {
+		licenseFiles = append(licenseFiles, f.Path+" "+f.License)
+	}
+	if want := []string{"LICENSE.md GPL-3.0", "docs/COPYING MIT"}; !reflect.DeepEqual(licenseFiles, want) {
+		t.Errorf("license files: got %q, want %q", licenseFiles, want)
+	}
+	if len(report.Files) != 1 || report.Files[0].Path != "license.go" || report.Files[0].Governing != "GPL-3.0" {
+		t.Errorf("source files: got %+v, want license.go governed by LICENSE.md", report.Files)
+	}
+}
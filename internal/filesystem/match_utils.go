@@ -0,0 +1,240 @@
+package filesystem
+
+import (
+	"errors"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Matcher проверяет, подходит ли путь (относительный, со слешами) под условие поиска.
+// Score используется для ранжирования результатов нечёткого поиска.
+type Matcher interface {
+	Match(path string) (score int, ok bool)
+}
+
+type MatchMode int
+
+const (
+	MatchGlob MatchMode = iota
+	MatchRegex
+	MatchFuzzy
+)
+
+type MatchOptions struct {
+	Mode       MatchMode
+	IgnoreCase bool
+	FullPath   bool
+}
+
+// NewMatcher собирает Matcher из нескольких шаблонов, объединённых через ИЛИ.
+func NewMatcher(patterns []string, opts MatchOptions) (Matcher, error) {
+	if len(patterns) == 0 {
+		return nil, errors.New("no search patterns given")
+	}
+
+	matchers := make(anyMatcher, 0, len(patterns))
+	for _, pattern := range patterns {
+		var m Matcher
+		var err error
+		switch opts.Mode {
+		case MatchRegex:
+			m, err = newRegexMatcher(pattern, opts.IgnoreCase)
+		case MatchFuzzy:
+			m = newFuzzyMatcher(pattern, opts.IgnoreCase)
+		default:
+			m, err = newGlobMatcher(pattern, opts.IgnoreCase)
+		}
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+
+	var m Matcher = matchers
+	if len(matchers) == 1 {
+		m = matchers[0]
+	}
+	if !opts.FullPath {
+		m = baseNameMatcher{m}
+	}
+	return m, nil
+}
+
+type anyMatcher []Matcher
+
+func (m anyMatcher) Match(p string) (int, bool) {
+	best, found := 0, false
+	for _, matcher := range m {
+		if score, ok := matcher.Match(p); ok && (!found || score > best) {
+			best, found = score, true
+		}
+	}
+	return best, found
+}
+
+type baseNameMatcher struct {
+	Matcher
+}
+
+func (m baseNameMatcher) Match(p string) (int, bool) {
+	return m.Matcher.Match(path.Base(p))
+}
+
+type globMatcher struct {
+	pattern    string
+	ignoreCase bool
+}
+
+func newGlobMatcher(pattern string, ignoreCase bool) (*globMatcher, error) {
+	if ignoreCase {
+		pattern = strings.ToLower(pattern)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	return &globMatcher{pattern: pattern, ignoreCase: ignoreCase}, nil
+}
+
+func (m *globMatcher) Match(p string) (int, bool) {
+	if m.ignoreCase {
+		p = strings.ToLower(p)
+	}
+	matched, _ := path.Match(m.pattern, p)
+	return 0, matched
+}
+
+type regexMatcher struct {
+	re *regexp.Regexp
+}
+
+func newRegexMatcher(pattern string, ignoreCase bool) (*regexMatcher, error) {
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &regexMatcher{re: re}, nil
+}
+
+func (m *regexMatcher) Match(p string) (int, bool) {
+	return 0, m.re.MatchString(p)
+}
+
+const (
+	fuzzyScoreMatch       = 16
+	fuzzyBonusBoundary    = 8
+	fuzzyBonusConsecutive = 4
+	fuzzyBonusFirstChar   = 2
+	fuzzyPenaltyGapStart  = 3
+	fuzzyPenaltyGapExtend = 1
+)
+
+// fuzzyMatcher ищет символы шаблона как подпоследовательность (как fzf).
+// Регистр учитывается, только если в шаблоне есть заглавные буквы.
+type fuzzyMatcher struct {
+	pattern    []rune
+	ignoreCase bool
+}
+
+func newFuzzyMatcher(pattern string, ignoreCase bool) *fuzzyMatcher {
+	if !ignoreCase {
+		ignoreCase = strings.ToLower(pattern) == pattern
+	}
+	if ignoreCase {
+		pattern = strings.ToLower(pattern)
+	}
+	return &fuzzyMatcher{pattern: []rune(pattern), ignoreCase: ignoreCase}
+}
+
+func (m *fuzzyMatcher) Match(p string) (int, bool) {
+	text := []rune(p)
+	if len(m.pattern) == 0 {
+		return 0, true
+	}
+
+	fold := func(r rune) rune {
+		if m.ignoreCase {
+			return unicode.ToLower(r)
+		}
+		return r
+	}
+
+	// Прямой проход находит конец первого вхождения подпоследовательности,
+	// обратный — сужает окно до кратчайшего.
+	pi, end := 0, -1
+	for i, r := range text {
+		if fold(r) == m.pattern[pi] {
+			pi++
+			if pi == len(m.pattern) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, false
+	}
+
+	start := end
+	pi = len(m.pattern) - 1
+	for i := end; i >= 0; i-- {
+		if fold(text[i]) == m.pattern[pi] {
+			pi--
+			if pi < 0 {
+				start = i
+				break
+			}
+		}
+	}
+
+	score := 0
+	pi = 0
+	consecutive := false
+	inGap := false
+	for i := start; i <= end; i++ {
+		if pi < len(m.pattern) && fold(text[i]) == m.pattern[pi] {
+			bonus := boundaryBonus(text, i)
+			if consecutive {
+				bonus += fuzzyBonusConsecutive
+			}
+			if pi == 0 {
+				bonus *= fuzzyBonusFirstChar
+			}
+			score += fuzzyScoreMatch + bonus
+			consecutive = true
+			inGap = false
+			pi++
+			continue
+		}
+
+		consecutive = false
+		if inGap {
+			score -= fuzzyPenaltyGapExtend
+		} else {
+			score -= fuzzyPenaltyGapStart
+			inGap = true
+		}
+	}
+
+	return score, true
+}
+
+func boundaryBonus(text []rune, i int) int {
+	if i == 0 {
+		return fuzzyBonusBoundary
+	}
+	prev, cur := text[i-1], text[i]
+	switch {
+	case strings.ContainsRune("/\\_-. ", prev):
+		return fuzzyBonusBoundary
+	case unicode.IsLower(prev) && unicode.IsUpper(cur):
+		return fuzzyBonusBoundary
+	case !unicode.IsDigit(prev) && unicode.IsDigit(cur):
+		return fuzzyBonusBoundary / 2
+	}
+	return 0
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package filesystem
+
+import "errors"
+
+func ownerPredicate(string) (Predicate, error) {
+	return nil, errors.New("predicate owner is not supported on this platform")
+}
@@ -0,0 +1,29 @@
+//go:build unix
+
+package filesystem
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+func ownerPredicate(owner string) (Predicate, error) {
+	uid, err := strconv.ParseUint(owner, 10, 32)
+	if err != nil {
+		u, lookupErr := user.Lookup(owner)
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+		uid, err = strconv.ParseUint(u.Uid, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return PredicateFunc(func(_ string, info os.FileInfo) bool {
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		return ok && uint64(stat.Uid) == uid
+	}), nil
+}
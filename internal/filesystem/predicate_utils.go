@@ -0,0 +1,396 @@
+package filesystem
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/SHCDevelops/file-manager/lib/utils"
+)
+
+// Predicate проверяет метаданные найденного файла (в духе find).
+type Predicate interface {
+	Test(path string, info os.FileInfo) bool
+}
+
+type PredicateFunc func(path string, info os.FileInfo) bool
+
+func (f PredicateFunc) Test(path string, info os.FileInfo) bool {
+	return f(path, info)
+}
+
+type andPredicate []Predicate
+
+func (p andPredicate) Test(path string, info os.FileInfo) bool {
+	for _, pred := range p {
+		if !pred.Test(path, info) {
+			return false
+		}
+	}
+	return true
+}
+
+type orPredicate []Predicate
+
+func (p orPredicate) Test(path string, info os.FileInfo) bool {
+	for _, pred := range p {
+		if pred.Test(path, info) {
+			return true
+		}
+	}
+	return false
+}
+
+type notPredicate struct {
+	Predicate
+}
+
+func (p notPredicate) Test(path string, info os.FileInfo) bool {
+	return !p.Predicate.Test(path, info)
+}
+
+func And(preds ...Predicate) Predicate {
+	if len(preds) == 1 {
+		return preds[0]
+	}
+	return andPredicate(preds)
+}
+
+func Or(preds ...Predicate) Predicate {
+	if len(preds) == 1 {
+		return preds[0]
+	}
+	return orPredicate(preds)
+}
+
+func Not(pred Predicate) Predicate {
+	return notPredicate{pred}
+}
+
+// NewPredicate создаёт предикат по имени и значению:
+// type (f|d|l), size ([+-]N[kMG]), mtime ([+-]N[smhdw]), perm ([-/]MODE),
+// owner (имя или uid), empty, newer (путь к файлу).
+func NewPredicate(name, value string) (Predicate, error) {
+	switch name {
+	case "type":
+		return typePredicate(value)
+	case "size":
+		return sizePredicate(value)
+	case "mtime":
+		return mtimePredicate(value, time.Now())
+	case "perm":
+		return permPredicate(value)
+	case "owner":
+		return ownerPredicate(value)
+	case "empty":
+		if value != "" {
+			return nil, fmt.Errorf("predicate empty takes no value")
+		}
+		return PredicateFunc(isEmpty), nil
+	case "newer":
+		return newerPredicate(value)
+	}
+	return nil, fmt.Errorf("unknown predicate %q", name)
+}
+
+func typePredicate(value string) (Predicate, error) {
+	var preds []Predicate
+	for _, t := range strings.Split(value, ",") {
+		switch t {
+		case "f":
+			preds = append(preds, PredicateFunc(func(_ string, info os.FileInfo) bool {
+				return info.Mode().IsRegular()
+			}))
+		case "d":
+			preds = append(preds, PredicateFunc(func(_ string, info os.FileInfo) bool {
+				return info.IsDir()
+			}))
+		case "l":
+			preds = append(preds, PredicateFunc(func(_ string, info os.FileInfo) bool {
+				return info.Mode()&os.ModeSymlink != 0
+			}))
+		default:
+			return nil, fmt.Errorf("invalid type %q, expected f, d or l", t)
+		}
+	}
+	return Or(preds...), nil
+}
+
+// splitSign отделяет ведущий + или - (больше/меньше) от значения.
+func splitSign(value string) (int, string) {
+	switch {
+	case strings.HasPrefix(value, "+"):
+		return 1, value[1:]
+	case strings.HasPrefix(value, "-"):
+		return -1, value[1:]
+	}
+	return 0, value
+}
+
+func sizePredicate(value string) (Predicate, error) {
+	sign, rest := splitSign(value)
+	n, unit, err := utils.SplitSize(rest)
+	if err != nil {
+		return nil, err
+	}
+	limit := n * unit
+
+	return PredicateFunc(func(_ string, info os.FileInfo) bool {
+		size := info.Size()
+		switch sign {
+		case 1:
+			return size > limit
+		case -1:
+			return size < limit
+		}
+		// Как в find: размер округляется вверх до единицы измерения.
+		return (size+unit-1)/unit == n
+	}), nil
+}
+
+var durationUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+func mtimePredicate(value string, now time.Time) (Predicate, error) {
+	sign, rest := splitSign(value)
+	numEnd := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsDigit(r) })
+	if numEnd < 0 {
+		numEnd = len(rest)
+	}
+	suffix := rest[numEnd:]
+	if suffix == "" {
+		suffix = "d"
+	}
+	unit, ok := durationUnits[suffix]
+	n, err := strconv.Atoi(rest[:numEnd])
+	if !ok || err != nil {
+		return nil, fmt.Errorf("invalid mtime %q", value)
+	}
+	limit := time.Duration(n) * unit
+
+	return PredicateFunc(func(_ string, info os.FileInfo) bool {
+		age := now.Sub(info.ModTime())
+		switch sign {
+		case 1:
+			return age > limit
+		case -1:
+			return age < limit
+		}
+		return age >= limit && age < limit+unit
+	}), nil
+}
+
+func permPredicate(value string) (Predicate, error) {
+	mode := value
+	var kind byte
+	if strings.HasPrefix(value, "-") || strings.HasPrefix(value, "/") {
+		kind, mode = value[0], value[1:]
+	}
+	bits, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || bits > 0o7777 {
+		return nil, fmt.Errorf("invalid perm %q, expected octal mode", value)
+	}
+	perm := os.FileMode(bits)
+
+	return PredicateFunc(func(_ string, info os.FileInfo) bool {
+		actual := info.Mode().Perm()
+		switch kind {
+		case '-':
+			return actual&perm == perm
+		case '/':
+			return perm == 0 || actual&perm != 0
+		}
+		return actual == perm
+	}), nil
+}
+
+func newerPredicate(ref string) (Predicate, error) {
+	refInfo, err := os.Stat(ref)
+	if err != nil {
+		return nil, err
+	}
+	refTime := refInfo.ModTime()
+
+	return PredicateFunc(func(_ string, info os.FileInfo) bool {
+		return info.ModTime().After(refTime)
+	}), nil
+}
+
+// entryInfo передаётся предикатам при обходе fs.FS: по нему empty читает
+// директорию через ту же файловую систему, а не с диска.
+type entryInfo struct {
+	os.FileInfo
+	fsys fs.FS
+	name string
+}
+
+func isEmpty(path string, info os.FileInfo) bool {
+	if info.Mode().IsRegular() {
+		return info.Size() == 0
+	}
+	if !info.IsDir() {
+		return false
+	}
+
+	var dir fs.File
+	var err error
+	if e, ok := info.(entryInfo); ok {
+		dir, err = e.fsys.Open(e.name)
+	} else {
+		dir, err = os.Open(path)
+	}
+	if err != nil {
+		return false
+	}
+	defer dir.Close()
+
+	if d, ok := dir.(fs.ReadDirFile); ok {
+		_, err = d.ReadDir(1)
+		return err == io.EOF
+	}
+	return false
+}
+
+// ParsePredicate разбирает выражение вида
+// "type=f and (size=+10M or not mtime=-7d)".
+// Операторы: and (или пробел), or, not, скобки; также &&, || и !.
+func ParsePredicate(expr string) (Predicate, error) {
+	p := &predicateParser{tokens: tokenizePredicate(expr)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty predicate expression")
+	}
+	pred, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in predicate expression", p.tokens[p.pos])
+	}
+	return pred, nil
+}
+
+func tokenizePredicate(expr string) []string {
+	var tokens []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			flush()
+		case c == '(' || c == ')':
+			flush()
+			tokens = append(tokens, string(c))
+		case c == '!' && cur.Len() == 0:
+			tokens = append(tokens, "not")
+		case strings.HasPrefix(expr[i:], "&&"):
+			flush()
+			tokens = append(tokens, "and")
+			i++
+		case strings.HasPrefix(expr[i:], "||"):
+			flush()
+			tokens = append(tokens, "or")
+			i++
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	flush()
+	return tokens
+}
+
+type predicateParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *predicateParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return strings.ToLower(p.tokens[p.pos])
+	}
+	return ""
+}
+
+func (p *predicateParser) parseOr() (Predicate, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	preds := []Predicate{left}
+	for p.peek() == "or" {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, right)
+	}
+	return Or(preds...), nil
+}
+
+func (p *predicateParser) parseAnd() (Predicate, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	preds := []Predicate{left}
+	for {
+		switch p.peek() {
+		case "and":
+			p.pos++
+		case "", "or", ")":
+			return And(preds...), nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, right)
+	}
+}
+
+func (p *predicateParser) parseUnary() (Predicate, error) {
+	switch p.peek() {
+	case "":
+		return nil, fmt.Errorf("unexpected end of predicate expression")
+	case "not":
+		p.pos++
+		pred, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(pred), nil
+	case "(":
+		p.pos++
+		pred, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing ) in predicate expression")
+		}
+		p.pos++
+		return pred, nil
+	}
+
+	token := p.tokens[p.pos]
+	p.pos++
+	name, value, _ := strings.Cut(token, "=")
+	return NewPredicate(strings.ToLower(name), value)
+}
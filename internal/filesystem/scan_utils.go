@@ -0,0 +1,130 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"sync"
+
+	"github.com/SHCDevelops/file-manager/internal/progress"
+)
+
+type ScanOptions struct {
+	IgnoreList []string
+	// Strict прерывает сканирование на первой ошибке. По умолчанию
+	// недоступные файлы пропускаются и собираются в ScanErrors.
+	Strict bool
+	// Progress, если задан, получает количество просмотренных файлов и байт.
+	Progress *progress.Counter
+	// OnError вызывается для каждой пропущенной записи по мере сканирования.
+	OnError func(*ScanError)
+	// Concurrency — число параллельно обрабатываемых файлов (0 — по умолчанию).
+	Concurrency int
+	// HashAlgorithm используется FindDuplicates (по умолчанию md5).
+	HashAlgorithm HashAlgorithm
+	// Archives включает обход содержимого zip, tar и tar.gz как директорий.
+	Archives bool
+	// Include, если задан, оставляет только записи, для которых он вернул true
+	// (путь — от корня сканирования со слешами). Содержимое архивов не фильтруется.
+	Include func(path string, isDir bool) bool
+	// IncludeGenerated и IncludeVendored учитывают в статистике кода
+	// сгенерированные и сторонние файлы; по умолчанию они попадают в CodeStats.Excluded.
+	IncludeGenerated bool
+	IncludeVendored  bool
+	// Complexity включает в CountCodeLines метрики функций Go (см. FunctionStat).
+	Complexity bool
+}
+
+// walkOptions возвращает параметры обхода и набор архивов, который нужно
+// закрыть, когда записи больше не читаются.
+func (o ScanOptions) walkOptions() (walkOptions, *archiveSet) {
+	archives := newArchiveSet(o.Archives)
+	return walkOptions{Ignore: o.IgnoreList, Include: o.Include, Archives: archives}, archives
+}
+
+func (o ScanOptions) concurrency(def int) int {
+	if o.Concurrency > 0 {
+		return o.Concurrency
+	}
+	return def
+}
+
+// scanFiles обходит target и для каждого файла вызывает visit; непустую
+// задачу, которую тот вернул, выполняет в пуле из opts.concurrency(10)
+// горутин. Ошибка задачи пропускает файл как ошибка чтения, успешно
+// обработанные файлы учитываются в opts.Progress. Возвращает сборщик
+// пропущенных записей (его finish вызывается после обработки результата)
+// или ошибку, прервавшую сканирование.
+func scanFiles(ctx context.Context, target scanTarget, opts ScanOptions, visit func(e walkEntry) func() error) (*errorCollector, error) {
+	var wg sync.WaitGroup
+	errs := newErrorCollector(opts)
+	semaphore := make(chan struct{}, opts.concurrency(10))
+
+	walkOpts, archives := opts.walkOptions()
+	defer archives.close()
+
+	errWalk := walk(ctx, target, walkOpts, func(e walkEntry, err error) error {
+		if err != nil {
+			return errs.add(e.path, "walk", err)
+		}
+		if errs.failed() {
+			return errs.err()
+		}
+		if e.info.IsDir() {
+			return nil
+		}
+		task := visit(e)
+		if task == nil {
+			return nil
+		}
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			if err := task(); err != nil {
+				errs.add(e.path, "read", err)
+				return
+			}
+			opts.Progress.AddFiles(1)
+			opts.Progress.AddBytes(e.info.Size())
+		}()
+		return nil
+	})
+
+	wg.Wait()
+	if errWalk != nil && ctx.Err() == nil {
+		return nil, errWalk
+	}
+	if errs.failed() {
+		return nil, errs.err()
+	}
+	return errs, nil
+}
+
+type HashAlgorithm string
+
+const (
+	HashMD5    HashAlgorithm = "md5"
+	HashSHA1   HashAlgorithm = "sha1"
+	HashSHA256 HashAlgorithm = "sha256"
+	HashSHA512 HashAlgorithm = "sha512"
+)
+
+func newHash(algorithm HashAlgorithm) (hash.Hash, error) {
+	switch algorithm {
+	case "", HashMD5:
+		return md5.New(), nil
+	case HashSHA1:
+		return sha1.New(), nil
+	case HashSHA256:
+		return sha256.New(), nil
+	case HashSHA512:
+		return sha512.New(), nil
+	}
+	return nil, fmt.Errorf("unknown hash algorithm %q, expected md5, sha1, sha256 or sha512", algorithm)
+}
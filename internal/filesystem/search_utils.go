@@ -1,67 +1,147 @@
 package filesystem
 
 import (
-	"github.com/SHCDevelops/file-manager/lib/utils"
-	"os"
-	"path/filepath"
-	"sync"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"time"
 )
 
-func SearchFiles(dir string, pattern string, ignoreList []string) ([]string, error) {
-	var matchedFiles []string
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-	var errors []error
+type SearchOptions struct {
+	ScanOptions
+	Matcher   Matcher
+	Predicate Predicate
+	Follow    bool
+	// MaxResults останавливает обход после N найденных записей (0 — без ограничения).
+	MaxResults int
+}
 
-	worker := func(path string, info os.FileInfo) {
-		defer wg.Done()
+type SearchResult struct {
+	Path    string
+	Type    EntryType
+	Size    int64
+	ModTime time.Time
+	Score   int
+}
 
-		if utils.IsIgnored(path, ignoreList, false) {
-			return
-		}
+// StreamSearch вызывает fn для каждой найденной записи по мере обхода dir.
+// Записи приходят в лексическом порядке путей, fn вызывается последовательно.
+// Если fn вернёт filepath.SkipAll, поиск завершится без ошибки.
+// Недоступные записи пропускаются и возвращаются в ScanErrors (кроме режима Strict).
+// При отмене ctx обход прерывается, возвращается ctx.Err().
+func StreamSearch(ctx context.Context, dir string, opts SearchOptions, fn func(SearchResult) error) error {
+	return streamSearch(ctx, hostTarget(dir), opts, fn)
+}
+
+// StreamSearchFS работает как StreamSearch, но обходит fsys; пути в результатах —
+// имена внутри fsys.
+func StreamSearchFS(ctx context.Context, fsys fs.FS, opts SearchOptions, fn func(SearchResult) error) error {
+	return streamSearch(ctx, fsTarget(fsys), opts, fn)
+}
+
+func streamSearch(ctx context.Context, target scanTarget, opts SearchOptions, fn func(SearchResult) error) error {
+	found := 0
+	errs := newErrorCollector(opts.ScanOptions)
+
+	walkOpts, archives := opts.walkOptions()
+	defer archives.close()
+	walkOpts.Follow = opts.Follow
 
-		matched, err := filepath.Match(pattern, filepath.Base(path))
+	err := walk(ctx, target, walkOpts, func(e walkEntry, err error) error {
 		if err != nil {
-			mu.Lock()
-			errors = append(errors, err)
-			mu.Unlock()
-			return
+			return errs.add(e.path, "walk", err)
 		}
+		if e.isRoot() {
+			return nil
+		}
+		opts.Progress.AddFiles(1)
 
-		if matched {
-			mu.Lock()
-			matchedFiles = append(matchedFiles, path)
-			mu.Unlock()
+		score, ok := opts.Matcher.Match(e.rel)
+		if !ok {
+			return nil
+		}
+		if opts.Predicate != nil && !opts.Predicate.Test(e.path, entryInfo{FileInfo: e.info, fsys: e.fsys, name: e.name}) {
+			return nil
 		}
-	}
 
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
+		if err := fn(SearchResult{
+			Path:    e.path,
+			Type:    entryType(e.info),
+			Size:    e.info.Size(),
+			ModTime: e.info.ModTime(),
+			Score:   score,
+		}); err != nil {
 			return err
 		}
 
-		if info.IsDir() {
-			if utils.IsIgnored(path, ignoreList, true) {
-				return filepath.SkipDir
-			}
-			return nil
+		found++
+		if opts.MaxResults > 0 && found >= opts.MaxResults {
+			return fs.SkipAll
 		}
-
-		wg.Add(1)
-		go worker(path, info)
 		return nil
 	})
+	if err != nil && ctx.Err() == nil {
+		return err
+	}
+	return errs.finish(ctx)
+}
+
+// SearchFiles собирает результаты StreamSearch. Вместе с найденными записями
+// может вернуться ScanErrors со списком пропущенных или ctx.Err() при отмене.
+func SearchFiles(ctx context.Context, dir string, opts SearchOptions) ([]SearchResult, error) {
+	return collectSearch(ctx, func(fn func(SearchResult) error) error {
+		return StreamSearch(ctx, dir, opts, fn)
+	})
+}
 
-	wg.Wait()
+// SearchFilesFS собирает результаты StreamSearchFS.
+func SearchFilesFS(ctx context.Context, fsys fs.FS, opts SearchOptions) ([]SearchResult, error) {
+	return collectSearch(ctx, func(fn func(SearchResult) error) error {
+		return StreamSearchFS(ctx, fsys, opts, fn)
+	})
+}
 
-	mu.Lock()
-	defer mu.Unlock()
-	if err != nil {
+func collectSearch(ctx context.Context, stream func(fn func(SearchResult) error) error) ([]SearchResult, error) {
+	var matchedFiles []SearchResult
+	err := stream(func(result SearchResult) error {
+		matchedFiles = append(matchedFiles, result)
+		return nil
+	})
+	var scanErrs ScanErrors
+	if err != nil && !errors.As(err, &scanErrs) && ctx.Err() == nil {
 		return nil, err
 	}
-	if len(errors) > 0 {
-		return nil, errors[0]
+	return matchedFiles, err
+}
+
+// SortSearchResults упорядочивает результаты: path — по пути, size — от больших
+// к меньшим, mtime — от новых к старым, score — по релевантности.
+// При равенстве ключей порядок определяется путём.
+func SortSearchResults(results []SearchResult, by string) error {
+	var less func(a, b SearchResult) bool
+	switch by {
+	case "path":
+		less = func(a, b SearchResult) bool { return false }
+	case "size":
+		less = func(a, b SearchResult) bool { return a.Size > b.Size }
+	case "mtime":
+		less = func(a, b SearchResult) bool { return a.ModTime.After(b.ModTime) }
+	case "score":
+		less = func(a, b SearchResult) bool { return a.Score > b.Score }
+	default:
+		return fmt.Errorf("unknown sort key %q, expected path, size, mtime or score", by)
 	}
 
-	return matchedFiles, nil
+	sort.SliceStable(results, func(i, j int) bool {
+		if less(results[i], results[j]) {
+			return true
+		}
+		if less(results[j], results[i]) {
+			return false
+		}
+		return results[i].Path < results[j].Path
+	})
+	return nil
 }
@@ -0,0 +1,179 @@
+package filesystem
+
+import (
+	"context"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func searchFixture() fstest.MapFS {
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	return fstest.MapFS{
+		"main.go":             {Data: []byte("package main\n"), ModTime: time.Now()},
+		"README.md":           {Data: make([]byte, 2048), ModTime: old},
+		"empty.txt":           {Data: nil, ModTime: old},
+		"src/app.go":          {Data: make([]byte, 4096), ModTime: time.Now()},
+		"src/app_test.go":     {Data: []byte("package src\n"), ModTime: old},
+		"src/vendor/lib.go":   {Data: []byte("package lib\n"), ModTime: old},
+		"docs/guide/intro.md": {Data: []byte("# Intro\n"), ModTime: old},
+		"empty":               {Mode: fs.ModeDir | 0o755},
+	}
+}
+
+func searchPaths(t *testing.T, patterns []string, match MatchOptions, pred Predicate, ignore ...string) []string {
+	t.Helper()
+	matcher, err := NewMatcher(patterns, match)
+	if err != nil {
+		t.Fatalf("NewMatcher(%q): %v", patterns, err)
+	}
+	results, err := SearchFilesFS(context.Background(), searchFixture(), SearchOptions{
+		ScanOptions: ScanOptions{IgnoreList: ignore},
+		Matcher:     matcher,
+		Predicate:   pred,
+	})
+	if err != nil {
+		t.Fatalf("SearchFilesFS: %v", err)
+	}
+	var paths []string
+	for _, r := range results {
+		paths = append(paths, r.Path)
+	}
+	return paths
+}
+
+func mustPredicate(t *testing.T, name, value string) Predicate {
+	t.Helper()
+	pred, err := NewPredicate(name, value)
+	if err != nil {
+		t.Fatalf("NewPredicate(%q, %q): %v", name, value, err)
+	}
+	return pred
+}
+
+func TestSearchFilesFS(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		match    MatchOptions
+		pred     func(t *testing.T) Predicate
+		ignore   []string
+		want     []string
+	}{
+		{
+			name:     "glob by name",
+			patterns: []string{"*.go"},
+			want:     []string{"main.go", "src/app.go", "src/app_test.go", "src/vendor/lib.go"},
+		},
+		{
+			name:     "several patterns",
+			patterns: []string{"*.md", "main.*"},
+			want:     []string{"README.md", "docs/guide/intro.md", "main.go"},
+		},
+		{
+			name:     "full path",
+			patterns: []string{"src/*.go"},
+			match:    MatchOptions{FullPath: true},
+			want:     []string{"src/app.go", "src/app_test.go"},
+		},
+		{
+			name:     "regex ignoring case",
+			patterns: []string{`^readme`},
+			match:    MatchOptions{Mode: MatchRegex, IgnoreCase: true},
+			want:     []string{"README.md"},
+		},
+		{
+			name:     "ignore is relative to the root",
+			patterns: []string{"*.go"},
+			ignore:   []string{"src/vendor"},
+			want:     []string{"main.go", "src/app.go", "src/app_test.go"},
+		},
+		{
+			name:     "type directory",
+			patterns: []string{"*"},
+			pred:     func(t *testing.T) Predicate { return mustPredicate(t, "type", "d") },
+			want:     []string{"docs", "docs/guide", "empty", "src", "src/vendor"},
+		},
+		{
+			name:     "size",
+			patterns: []string{"*"},
+			pred:     func(t *testing.T) Predicate { return mustPredicate(t, "size", "+1k") },
+			want:     []string{"README.md", "src/app.go"},
+		},
+		{
+			name:     "empty files and directories",
+			patterns: []string{"*"},
+			pred:     func(t *testing.T) Predicate { return mustPredicate(t, "empty", "") },
+			want:     []string{"empty", "empty.txt"},
+		},
+		{
+			name:     "mtime",
+			patterns: []string{"*.go"},
+			pred:     func(t *testing.T) Predicate { return mustPredicate(t, "mtime", "-7d") },
+			want:     []string{"main.go", "src/app.go"},
+		},
+		{
+			name:     "expression",
+			patterns: []string{"*"},
+			pred: func(t *testing.T) Predicate {
+				pred, err := ParsePredicate("type=f and not (size=+1k or empty)")
+				if err != nil {
+					t.Fatal(err)
+				}
+				return pred
+			},
+			want: []string{"docs/guide/intro.md", "main.go", "src/app_test.go", "src/vendor/lib.go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pred Predicate
+			if tt.pred != nil {
+				pred = tt.pred(t)
+			}
+			got := searchPaths(t, tt.patterns, tt.match, pred, tt.ignore...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchFilesFSMaxResults(t *testing.T) {
+	matcher, err := NewMatcher([]string{"*.go"}, MatchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := SearchFilesFS(context.Background(), searchFixture(), SearchOptions{Matcher: matcher, MaxResults: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Path != "main.go" || results[1].Path != "src/app.go" {
+		t.Errorf("got %+v, want the first two matches in path order", results)
+	}
+}
+
+func TestSortSearchResults(t *testing.T) {
+	results := []SearchResult{
+		{Path: "b", Size: 10, Score: 1},
+		{Path: "a", Size: 10, Score: 5},
+		{Path: "c", Size: 30, Score: 3},
+	}
+	if err := SortSearchResults(results, "size"); err != nil {
+		t.Fatal(err)
+	}
+	if got := []string{results[0].Path, results[1].Path, results[2].Path}; !reflect.DeepEqual(got, []string{"c", "a", "b"}) {
+		t.Errorf("by size: got %q", got)
+	}
+	if err := SortSearchResults(results, "score"); err != nil {
+		t.Fatal(err)
+	}
+	if got := []string{results[0].Path, results[1].Path, results[2].Path}; !reflect.DeepEqual(got, []string{"a", "c", "b"}) {
+		t.Errorf("by score: got %q", got)
+	}
+	if err := SortSearchResults(nil, "szie"); err == nil {
+		t.Error("unknown key: expected an error")
+	}
+}
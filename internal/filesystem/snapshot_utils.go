@@ -0,0 +1,391 @@
+package filesystem
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// snapshotVersion увеличивается при несовместимых изменениях формата.
+const snapshotVersion = 1
+
+// Snapshot — сохранённое состояние дерева файлов. Директории не хранятся:
+// их размеры вычисляются из файлов при сравнении.
+type Snapshot struct {
+	Version int
+	Root    string
+	Created time.Time
+	// HashAlgorithm пуст, если хеши не считались.
+	HashAlgorithm HashAlgorithm
+	// IgnoreList и Archives — параметры сканирования; с ними же снимок
+	// сравнивается с текущим состоянием дерева.
+	IgnoreList []string
+	Archives   bool
+	// Entries отсортированы по Path.
+	Entries []SnapshotEntry
+	// Skipped — пути записей, которые не удалось прочитать. DiffSnapshots
+	// не сравнивает ни их, ни то, что лежит внутри.
+	Skipped []string
+}
+
+type SnapshotEntry struct {
+	// Path — путь от корня снимка со слешами.
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Mode    fs.FileMode
+	// Hash пуст, если хеши не считались или файл не удалось прочитать.
+	Hash string
+}
+
+// TakeSnapshot сканирует dir. Если hash задан, для обычных файлов считается
+// хеш содержимого. Недоступные записи пропускаются и возвращаются в ScanErrors.
+func TakeSnapshot(ctx context.Context, dir string, hash HashAlgorithm, opts ScanOptions) (*Snapshot, error) {
+	snapshot, err := takeSnapshot(ctx, hostTarget(dir), hash, opts)
+	if snapshot != nil {
+		if abs, absErr := filepath.Abs(dir); absErr == nil {
+			snapshot.Root = abs
+		}
+	}
+	return snapshot, err
+}
+
+// TakeSnapshotFS работает как TakeSnapshot, но обходит fsys.
+func TakeSnapshotFS(ctx context.Context, fsys fs.FS, hash HashAlgorithm, opts ScanOptions) (*Snapshot, error) {
+	return takeSnapshot(ctx, fsTarget(fsys), hash, opts)
+}
+
+func takeSnapshot(ctx context.Context, target scanTarget, hash HashAlgorithm, opts ScanOptions) (*Snapshot, error) {
+	if hash != "" {
+		if _, err := newHash(hash); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshot := &Snapshot{
+		Version:       snapshotVersion,
+		Root:          target.dir,
+		Created:       time.Now(),
+		HashAlgorithm: hash,
+		IgnoreList:    opts.IgnoreList,
+		Archives:      opts.Archives,
+	}
+	var toHash []walkEntry
+	var toHashIdx []int
+	var totalBytes int64
+	errs := newErrorCollector(opts)
+
+	walkOpts, archives := opts.walkOptions()
+	defer archives.close()
+
+	opts.Progress.SetPhase("scanning", 0)
+	err := walk(ctx, target, walkOpts, func(e walkEntry, err error) error {
+		if err != nil {
+			skipped := e.rel
+			if e.info != nil && !e.info.IsDir() {
+				// Не открылся архив: сам файл в снимке есть, пропущено содержимое.
+				skipped += archiveSeparator
+			}
+			snapshot.Skipped = append(snapshot.Skipped, skipped)
+			return errs.add(e.path, "walk", err)
+		}
+		if e.info.IsDir() {
+			return nil
+		}
+
+		opts.Progress.AddFiles(1)
+		if hash != "" && e.info.Mode().IsRegular() {
+			toHash = append(toHash, e)
+			toHashIdx = append(toHashIdx, len(snapshot.Entries))
+			totalBytes += e.info.Size()
+		}
+		snapshot.Entries = append(snapshot.Entries, SnapshotEntry{
+			Path:    e.rel,
+			Size:    e.info.Size(),
+			ModTime: e.info.ModTime(),
+			Mode:    e.info.Mode(),
+		})
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		return nil, err
+	}
+
+	if len(toHash) > 0 {
+		opts.Progress.SetPhase("hashing", totalBytes)
+		jobs := make(chan int)
+		var wg sync.WaitGroup
+		for i := 0; i < opts.concurrency(runtime.NumCPU()); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					e := toHash[i]
+					sum, err := hashFile(ctx, e.fsys, e.name, hash, opts.Progress)
+					if err != nil {
+						if ctx.Err() == nil {
+							errs.add(e.path, "hash", err)
+						}
+						continue
+					}
+					snapshot.Entries[toHashIdx[i]].Hash = sum
+				}
+			}()
+		}
+		for i := range toHash {
+			if ctx.Err() != nil || errs.failed() {
+				break
+			}
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+	}
+
+	if errs.failed() {
+		return nil, errs.err()
+	}
+	sort.Slice(snapshot.Entries, func(i, j int) bool {
+		return snapshot.Entries[i].Path < snapshot.Entries[j].Path
+	})
+	sort.Strings(snapshot.Skipped)
+	return snapshot, errs.finish(ctx)
+}
+
+// WriteSnapshot сохраняет снимок в сжатом бинарном виде (gob + gzip).
+func WriteSnapshot(w io.Writer, snapshot *Snapshot) error {
+	gz := gzip.NewWriter(w)
+	if err := gob.NewEncoder(gz).Encode(snapshot); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ReadSnapshot читает снимок, сохранённый WriteSnapshot.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a snapshot file: %w", err)
+	}
+	defer gz.Close()
+
+	var snapshot Snapshot
+	if err := gob.NewDecoder(gz).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("not a snapshot file: %w", err)
+	}
+	if snapshot.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", snapshot.Version)
+	}
+	return &snapshot, nil
+}
+
+type EntryChange struct {
+	Old, New SnapshotEntry
+}
+
+type DirGrowth struct {
+	Path             string
+	OldSize, NewSize int64
+}
+
+func (g DirGrowth) Delta() int64 {
+	return g.NewSize - g.OldSize
+}
+
+type SnapshotDiff struct {
+	Added    []SnapshotEntry
+	Removed  []SnapshotEntry
+	Modified []EntryChange
+	Moved    []EntryChange
+	// Dirs — директории, размер которых изменился, по убыванию |Delta|.
+	Dirs []DirGrowth
+}
+
+func (d *SnapshotDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0 && len(d.Moved) == 0
+}
+
+// DiffSnapshots сравнивает два снимка. Перемещённым считается файл, который
+// исчез по одному пути и появился по другому с тем же хешем, а если хешей нет
+// в одном из снимков — с тем же размером и временем изменения. Пути, пропущенные
+// при сканировании любого из снимков, не сравниваются.
+func DiffSnapshots(before, after *Snapshot) (*SnapshotDiff, error) {
+	if !sortedByPath(before.Entries) || !sortedByPath(after.Entries) {
+		return nil, errors.New("snapshot entries are not sorted by path")
+	}
+
+	skipped := append(append([]string(nil), before.Skipped...), after.Skipped...)
+	oldEntries := withoutSkipped(before.Entries, skipped)
+	newEntries := withoutSkipped(after.Entries, skipped)
+
+	diff := &SnapshotDiff{}
+	compareHashes := before.HashAlgorithm != "" && before.HashAlgorithm == after.HashAlgorithm
+
+	i, j := 0, 0
+	for i < len(oldEntries) || j < len(newEntries) {
+		switch {
+		case j == len(newEntries) || (i < len(oldEntries) && oldEntries[i].Path < newEntries[j].Path):
+			diff.Removed = append(diff.Removed, oldEntries[i])
+			i++
+		case i == len(oldEntries) || newEntries[j].Path < oldEntries[i].Path:
+			diff.Added = append(diff.Added, newEntries[j])
+			j++
+		default:
+			if entryChanged(oldEntries[i], newEntries[j], compareHashes) {
+				diff.Modified = append(diff.Modified, EntryChange{Old: oldEntries[i], New: newEntries[j]})
+			}
+			i++
+			j++
+		}
+	}
+
+	diff.detectMoves(compareHashes)
+	diff.Dirs = dirGrowth(oldEntries, newEntries)
+	return diff, nil
+}
+
+// withoutSkipped возвращает записи, не совпадающие ни с одним из путей skipped
+// и не лежащие внутри них.
+func withoutSkipped(entries []SnapshotEntry, skipped []string) []SnapshotEntry {
+	if len(skipped) == 0 {
+		return entries
+	}
+	var result []SnapshotEntry
+	for _, e := range entries {
+		if !underAny(e.Path, skipped) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func underAny(p string, dirs []string) bool {
+	for _, dir := range dirs {
+		if dir == "." || p == dir || strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedByPath(entries []SnapshotEntry) bool {
+	return sort.SliceIsSorted(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+}
+
+func entryChanged(a, b SnapshotEntry, compareHashes bool) bool {
+	if a.Size != b.Size || a.Mode != b.Mode || !a.ModTime.Equal(b.ModTime) {
+		return true
+	}
+	// Пустой хеш — файл не удалось прочитать: сравнивать нечего.
+	return compareHashes && a.Hash != "" && b.Hash != "" && a.Hash != b.Hash
+}
+
+// moveKey — признак, по которому удалённый файл сопоставляется с добавленным.
+type moveKey struct {
+	hash    string
+	size    int64
+	modTime int64
+}
+
+func (d *SnapshotDiff) detectMoves(compareHashes bool) {
+	key := func(e SnapshotEntry) (moveKey, bool) {
+		if !e.Mode.IsRegular() {
+			return moveKey{}, false
+		}
+		if compareHashes {
+			return moveKey{hash: e.Hash}, e.Hash != ""
+		}
+		// Пустые файлы без хешей неотличимы друг от друга.
+		return moveKey{size: e.Size, modTime: e.ModTime.UnixNano()}, e.Size > 0
+	}
+
+	removed := make(map[moveKey][]int)
+	for i, e := range d.Removed {
+		if k, ok := key(e); ok {
+			removed[k] = append(removed[k], i)
+		}
+	}
+
+	movedFrom := make(map[int]bool)
+	var added []SnapshotEntry
+	for _, e := range d.Added {
+		k, ok := key(e)
+		if candidates := removed[k]; ok && len(candidates) > 0 {
+			d.Moved = append(d.Moved, EntryChange{Old: d.Removed[candidates[0]], New: e})
+			movedFrom[candidates[0]] = true
+			removed[k] = candidates[1:]
+			continue
+		}
+		added = append(added, e)
+	}
+
+	var stillRemoved []SnapshotEntry
+	for i, e := range d.Removed {
+		if !movedFrom[i] {
+			stillRemoved = append(stillRemoved, e)
+		}
+	}
+	d.Added, d.Removed = added, stillRemoved
+}
+
+func dirGrowth(before, after []SnapshotEntry) []DirGrowth {
+	sizes := make(map[string]*DirGrowth)
+	add := func(p string, size int64, isAfter bool) {
+		for dir := path.Dir(p); ; dir = path.Dir(dir) {
+			g, ok := sizes[dir]
+			if !ok {
+				g = &DirGrowth{Path: dir}
+				sizes[dir] = g
+			}
+			if isAfter {
+				g.NewSize += size
+			} else {
+				g.OldSize += size
+			}
+			if dir == "." || dir == "/" {
+				break
+			}
+		}
+	}
+	for _, e := range before {
+		add(e.Path, e.Size, false)
+	}
+	for _, e := range after {
+		add(e.Path, e.Size, true)
+	}
+
+	var dirs []DirGrowth
+	for _, g := range sizes {
+		if g.Delta() != 0 {
+			dirs = append(dirs, *g)
+		}
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		a, b := abs(dirs[i].Delta()), abs(dirs[j].Delta())
+		if a != b {
+			return a > b
+		}
+		return dirs[i].Path < dirs[j].Path
+	})
+	return dirs
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,171 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestTakeSnapshotFSSkipped(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":      {Data: []byte("a")},
+		"broken.zip": {Data: []byte("not a zip")},
+	}
+	snapshot, err := TakeSnapshotFS(context.Background(), fsys, HashSHA256, ScanOptions{Archives: true})
+	var scanErrs ScanErrors
+	if !errors.As(err, &scanErrs) || len(scanErrs) != 1 {
+		t.Fatalf("got error %v, want a warning for broken.zip", err)
+	}
+	if want := []string{"broken.zip!"}; !reflect.DeepEqual(snapshot.Skipped, want) {
+		t.Errorf("skipped: got %q, want %q", snapshot.Skipped, want)
+	}
+	if len(snapshot.Entries) != 2 {
+		t.Errorf("got %d entries, want a.txt and broken.zip", len(snapshot.Entries))
+	}
+}
+
+func TestDiffSnapshotsSkipped(t *testing.T) {
+	mtime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	file := func(path, hash string) SnapshotEntry {
+		return SnapshotEntry{Path: path, Size: 1, ModTime: mtime, Hash: hash}
+	}
+
+	tests := []struct {
+		name          string
+		before, after *Snapshot
+		wantEmpty     bool
+	}{
+		{
+			name:      "unreadable directory in the new snapshot",
+			before:    &Snapshot{Entries: []SnapshotEntry{file("a", ""), file("locked/b", "")}},
+			after:     &Snapshot{Entries: []SnapshotEntry{file("a", "")}, Skipped: []string{"locked"}},
+			wantEmpty: true,
+		},
+		{
+			name:      "unreadable archive in the old snapshot",
+			before:    &Snapshot{Entries: []SnapshotEntry{file("x.zip", "")}, Skipped: []string{"x.zip!"}},
+			after:     &Snapshot{Entries: []SnapshotEntry{file("x.zip", ""), file("x.zip!/c", "")}},
+			wantEmpty: true,
+		},
+		{
+			name:   "sibling with the same prefix is compared",
+			before: &Snapshot{Entries: []SnapshotEntry{file("locked2", "")}},
+			after:  &Snapshot{Skipped: []string{"locked"}},
+		},
+		{
+			name:      "hash of an unreadable file",
+			before:    &Snapshot{HashAlgorithm: HashSHA256, Entries: []SnapshotEntry{file("a", "1")}},
+			after:     &Snapshot{HashAlgorithm: HashSHA256, Entries: []SnapshotEntry{file("a", "")}},
+			wantEmpty: true,
+		},
+		{
+			name:   "different hashes",
+			before: &Snapshot{HashAlgorithm: HashSHA256, Entries: []SnapshotEntry{file("a", "1")}},
+			after:  &Snapshot{HashAlgorithm: HashSHA256, Entries: []SnapshotEntry{file("a", "2")}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := DiffSnapshots(tt.before, tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff.Empty() != tt.wantEmpty {
+				t.Errorf("got %+v, want empty %v", diff, tt.wantEmpty)
+			}
+		})
+	}
+}
+
+func TestDiffSnapshotsMoves(t *testing.T) {
+	mtime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := func(path string, size int64, modTime time.Time, hash string) SnapshotEntry {
+		return SnapshotEntry{Path: path, Size: size, ModTime: modTime, Hash: hash}
+	}
+
+	tests := []struct {
+		name string
+		// hashes — алгоритмы хешей старого и нового снимков.
+		hashes        [2]HashAlgorithm
+		before, after []SnapshotEntry
+		// moved — пары "старый путь → новый путь".
+		moved          []string
+		added, removed int
+	}{
+		{
+			name:   "same hash, different mtime",
+			hashes: [2]HashAlgorithm{HashSHA256, HashSHA256},
+			before: []SnapshotEntry{entry("a", 3, mtime, "h1")},
+			after:  []SnapshotEntry{entry("b", 3, mtime.Add(time.Hour), "h1")},
+			moved:  []string{"a → b"},
+		},
+		{
+			name:    "different hash, same size and mtime",
+			hashes:  [2]HashAlgorithm{HashSHA256, HashSHA256},
+			before:  []SnapshotEntry{entry("a", 3, mtime, "h1")},
+			after:   []SnapshotEntry{entry("b", 3, mtime, "h2")},
+			added:   1,
+			removed: 1,
+		},
+		{
+			name:   "no hashes, same size and mtime",
+			before: []SnapshotEntry{entry("a", 3, mtime, "")},
+			after:  []SnapshotEntry{entry("b", 3, mtime, "")},
+			moved:  []string{"a → b"},
+		},
+		{
+			name:    "no hashes, different mtime",
+			before:  []SnapshotEntry{entry("a", 3, mtime, "")},
+			after:   []SnapshotEntry{entry("b", 3, mtime.Add(time.Second), "")},
+			added:   1,
+			removed: 1,
+		},
+		{
+			name:   "hashes in one snapshot only, same size and mtime",
+			hashes: [2]HashAlgorithm{HashSHA256, ""},
+			before: []SnapshotEntry{entry("a", 3, mtime, "h1")},
+			after:  []SnapshotEntry{entry("b", 3, mtime, "")},
+			moved:  []string{"a → b"},
+		},
+		{
+			name:    "no hashes, empty files",
+			before:  []SnapshotEntry{entry("a", 0, mtime, "")},
+			after:   []SnapshotEntry{entry("b", 0, mtime, "")},
+			added:   1,
+			removed: 1,
+		},
+		{
+			name:   "each removed file matches one added file",
+			hashes: [2]HashAlgorithm{HashSHA256, HashSHA256},
+			before: []SnapshotEntry{entry("a", 3, mtime, "h1")},
+			after:  []SnapshotEntry{entry("b", 3, mtime, "h1"), entry("c", 3, mtime, "h1")},
+			moved:  []string{"a → b"},
+			added:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := DiffSnapshots(
+				&Snapshot{HashAlgorithm: tt.hashes[0], Entries: tt.before},
+				&Snapshot{HashAlgorithm: tt.hashes[1], Entries: tt.after},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var moved []string
+			for _, c := range diff.Moved {
+				moved = append(moved, c.Old.Path+" → "+c.New.Path)
+			}
+			if !reflect.DeepEqual(moved, tt.moved) {
+				t.Errorf("moved: got %q, want %q", moved, tt.moved)
+			}
+			if len(diff.Added) != tt.added || len(diff.Removed) != tt.removed {
+				t.Errorf("got %d added and %d removed, want %d and %d",
+					len(diff.Added), len(diff.Removed), tt.added, tt.removed)
+			}
+		})
+	}
+}
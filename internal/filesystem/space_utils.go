@@ -1,12 +1,9 @@
 package filesystem
 
 import (
-	"os"
-	"path/filepath"
+	"context"
+	"io/fs"
 	"sort"
-	"sync"
-
-	"github.com/SHCDevelops/file-manager/lib/utils"
 )
 
 type FileSize struct {
@@ -14,51 +11,43 @@ type FileSize struct {
 	Size int64
 }
 
-func AnalyzeSpace(dir string, top int, ignoreList []string) ([]FileSize, error) {
-	var files []FileSize
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-	var errors []error
+// AnalyzeSpace возвращает top самых больших файлов в dir. Недоступные записи
+// пропускаются и возвращаются в ScanErrors вместе с результатом.
+// При отмене ctx возвращается частичный результат вместе с ctx.Err().
+func AnalyzeSpace(ctx context.Context, dir string, top int, opts ScanOptions) ([]FileSize, error) {
+	return analyzeSpace(ctx, hostTarget(dir), top, opts)
+}
 
-	worker := func(path string, info os.FileInfo) {
-		defer wg.Done()
+// AnalyzeSpaceFS работает как AnalyzeSpace, но обходит fsys.
+func AnalyzeSpaceFS(ctx context.Context, fsys fs.FS, top int, opts ScanOptions) ([]FileSize, error) {
+	return analyzeSpace(ctx, fsTarget(fsys), top, opts)
+}
 
-		if utils.IsIgnored(path, ignoreList, false) {
-			return
-		}
+func analyzeSpace(ctx context.Context, target scanTarget, top int, opts ScanOptions) ([]FileSize, error) {
+	var files []FileSize
+	errs := newErrorCollector(opts)
+	opts.Progress.SetPhase("scanning", 0)
 
-		mu.Lock()
-		files = append(files, FileSize{Path: path, Size: info.Size()})
-		mu.Unlock()
-	}
+	walkOpts, archives := opts.walkOptions()
+	defer archives.close()
 
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+	err := walk(ctx, target, walkOpts, func(e walkEntry, err error) error {
 		if err != nil {
-			return err
+			return errs.add(e.path, "walk", err)
 		}
-
-		if info.IsDir() {
-			if utils.IsIgnored(path, ignoreList, true) {
-				return filepath.SkipDir
-			}
+		if e.info.IsDir() {
 			return nil
 		}
 
-		wg.Add(1)
-		go worker(path, info)
+		opts.Progress.AddFiles(1)
+		opts.Progress.AddBytes(e.info.Size())
+		files = append(files, FileSize{Path: e.path, Size: e.info.Size()})
 		return nil
 	})
 
-	wg.Wait()
-
-	mu.Lock()
-	defer mu.Unlock()
-	if err != nil {
+	if err != nil && ctx.Err() == nil {
 		return nil, err
 	}
-	if len(errors) > 0 {
-		return nil, errors[0]
-	}
 
 	sort.Slice(files, func(i, j int) bool {
 		return files[i].Size > files[j].Size
@@ -68,5 +57,5 @@ func AnalyzeSpace(dir string, top int, ignoreList []string) ([]FileSize, error)
 		files = files[:top]
 	}
 
-	return files, nil
+	return files, errs.finish(ctx)
 }
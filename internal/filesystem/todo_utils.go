@@ -0,0 +1,193 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultTodoTags — пометки, которые ищет FindTodos, если теги не заданы.
+var DefaultTodoTags = []string{"TODO", "FIXME", "HACK", "XXX"}
+
+// Todo — пометка в комментарии: тег, необязательный автор в скобках после
+// него и текст до конца строки.
+type Todo struct {
+	// Path — путь к файлу, как его выводят остальные команды.
+	Path string
+	// Line — номер строки с 1.
+	Line int
+	Tag  string
+	// Author — имя в скобках после тега; пустое, если его нет.
+	Author string
+	Text   string
+}
+
+// lineComments — начало однострочного комментария по языкам: по нему пометки
+// ищутся и в комментариях в конце строк кода.
+var lineComments = map[string]string{
+	"JavaScript": "//", "TypeScript": "//", "Java": "//", "C++": "//", "C": "//",
+	"PHP": "//", "Swift": "//", "Kotlin": "//", "Rust": "//", "Dart": "//",
+	"C#": "//", "Scala": "//", "Go": "//", "Pascal": "//",
+	"Python": "#", "Shell": "#", "Perl": "#", "YAML": "#", "Ruby": "#",
+	"Haskell": "--", "SQL": "--", "Lua": "--",
+}
+
+// commentClosers срезаются с конца текста пометки.
+var commentClosers = []string{"*/", "-->", "-}", "]]", "}"}
+
+// todoMatcher находит пометки с заданными тегами. Теги чувствительны
+// к регистру: "todo" в обычном тексте не считается пометкой.
+type todoMatcher struct {
+	re *regexp.Regexp
+}
+
+func newTodoMatcher(tags []string) (*todoMatcher, error) {
+	if len(tags) == 0 {
+		tags = DefaultTodoTags
+	}
+	quoted := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		quoted = append(quoted, regexp.QuoteMeta(tag))
+	}
+	if len(quoted) == 0 {
+		return nil, fmt.Errorf("no todo tags given")
+	}
+	re, err := regexp.Compile(`\b(` + strings.Join(quoted, "|") + `)\b(?:\(([^)]*)\))?:?(.*)`)
+	if err != nil {
+		return nil, err
+	}
+	return &todoMatcher{re: re}, nil
+}
+
+// match ищет пометку в тексте комментария.
+func (m *todoMatcher) match(comment string) (Todo, bool) {
+	sub := m.re.FindStringSubmatch(comment)
+	if sub == nil {
+		return Todo{}, false
+	}
+	text := strings.TrimSpace(sub[3])
+	for _, closer := range commentClosers {
+		text = strings.TrimSpace(strings.TrimSuffix(text, closer))
+	}
+	return Todo{Tag: sub[1], Author: strings.TrimSpace(sub[2]), Text: text}, true
+}
+
+// FindTodos ищет пометки tags (по умолчанию DefaultTodoTags) в комментариях
+// файлов поддерживаемых языков. Сгенерированные и сторонние файлы пропускаются,
+// если их не включают ScanOptions.IncludeGenerated и IncludeVendored. Результат
+// отсортирован по пути и номеру строки.
+func FindTodos(ctx context.Context, root string, tags, ignoreLanguages []string, opts ScanOptions) ([]Todo, error) {
+	return findTodos(ctx, hostTarget(root), tags, ignoreLanguages, opts)
+}
+
+// FindTodosFS работает как FindTodos, но обходит fsys.
+func FindTodosFS(ctx context.Context, fsys fs.FS, tags, ignoreLanguages []string, opts ScanOptions) ([]Todo, error) {
+	return findTodos(ctx, fsTarget(fsys), tags, ignoreLanguages, opts)
+}
+
+func findTodos(ctx context.Context, target scanTarget, tags, ignoreLanguages []string, opts ScanOptions) ([]Todo, error) {
+	matcher, err := newTodoMatcher(tags)
+	if err != nil {
+		return nil, err
+	}
+	ignoredLangs := make(map[string]bool)
+	for _, lang := range ignoreLanguages {
+		ignoredLangs[strings.ToLower(lang)] = true
+	}
+
+	var (
+		mu    sync.Mutex
+		todos []Todo
+	)
+	opts.Progress.SetPhase("searching", 0)
+	errs, err := scanFiles(ctx, target, opts, func(e walkEntry) func() error {
+		lang := getLanguage(e.path, ignoredLangs)
+		if lang == "" {
+			return nil
+		}
+		return func() error {
+			found, err := fileTodos(e, lang, ignoredLangs, matcher, opts)
+			if err != nil {
+				return err
+			}
+			if len(found) > 0 {
+				mu.Lock()
+				todos = append(todos, found...)
+				mu.Unlock()
+			}
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(todos, func(i, j int) bool {
+		if todos[i].Path != todos[j].Path {
+			return todos[i].Path < todos[j].Path
+		}
+		return todos[i].Line < todos[j].Line
+	})
+	return todos, errs.finish(ctx)
+}
+
+// fileTodos разбирает файл парсером его языка и собирает пометки из строк
+// комментариев и из комментариев в конце строк кода. Встроенные фрагменты
+// на языках из ignored пропускаются.
+func fileTodos(e walkEntry, lang string, ignored map[string]bool, matcher *todoMatcher, opts ScanOptions) ([]Todo, error) {
+	f, err := e.fsys.Open(e.name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var todos []Todo
+	found := func(n int, comment string) {
+		if todo, ok := matcher.match(comment); ok {
+			todo.Path, todo.Line = e.path, n
+			todos = append(todos, todo)
+		}
+	}
+	hookFor := func(lang string) (lineHook, bool) {
+		if ignored[strings.ToLower(lang)] {
+			return lineHook{}, false
+		}
+		hook := lineHook{onComment: found}
+		if lang == "Markdown" {
+			// Текст документации — не комментарии к коду; блоки кода в нём
+			// разбираются как отдельные части.
+			hook.onComment = nil
+		}
+		if marker := lineComments[lang]; marker != "" {
+			var quotes string
+			if rules := estimates[lang]; rules != nil {
+				quotes = rules.quotes
+			}
+			hook.onCode = func(n int, line string) {
+				// Строки вырезаются, чтобы не принять "http://..." за комментарий.
+				line = stripStrings(line, quotes)
+				if i := strings.Index(line, marker); i >= 0 {
+					found(n, line[i+len(marker):])
+				}
+			}
+		}
+		return hook, true
+	}
+
+	generated, err := scanLines(f, lang, hookFor)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.countsClass(classifyFile(e.rel, generated)) {
+		return nil, nil
+	}
+	sort.Slice(todos, func(i, j int) bool { return todos[i].Line < todos[j].Line })
+	return todos, nil
+}
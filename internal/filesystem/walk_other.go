@@ -0,0 +1,25 @@
+//go:build !unix
+
+package filesystem
+
+import (
+	"path/filepath"
+)
+
+// На платформах без inode директория опознаётся по пути без ссылок.
+// Для fs.FS, не связанных с диском, циклы отслеживаются только на диске.
+type fileID struct {
+	path string
+}
+
+func fileKey(e walkEntry) (fileID, bool) {
+	dir, ok := e.fsys.(*dirFS)
+	if !ok {
+		return fileID{}, false
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Join(dir.dir, filepath.FromSlash(e.name)))
+	if err != nil {
+		return fileID{}, false
+	}
+	return fileID{path: resolved}, true
+}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package filesystem
+
+import (
+	"syscall"
+)
+
+type fileID struct {
+	dev, ino uint64
+}
+
+func fileKey(e walkEntry) (fileID, bool) {
+	stat, ok := e.info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, false
+	}
+	return fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, true
+}
@@ -0,0 +1,217 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/SHCDevelops/file-manager/lib/utils"
+)
+
+type EntryType int
+
+const (
+	EntryFile EntryType = iota
+	EntryDir
+	EntrySymlink
+	EntryOther
+)
+
+func (t EntryType) String() string {
+	switch t {
+	case EntryFile:
+		return "file"
+	case EntryDir:
+		return "dir"
+	case EntrySymlink:
+		return "symlink"
+	}
+	return "other"
+}
+
+func entryType(info os.FileInfo) EntryType {
+	mode := info.Mode()
+	switch {
+	case mode.IsRegular():
+		return EntryFile
+	case mode.IsDir():
+		return EntryDir
+	case mode&os.ModeSymlink != 0:
+		return EntrySymlink
+	}
+	return EntryOther
+}
+
+type walkOptions struct {
+	// Follow включает переход по символическим ссылкам.
+	// Циклы обнаруживаются по (dev, inode) директорий на текущем пути обхода.
+	Follow bool
+	// Ignore — шаблоны исключений; подходящие записи не передаются в walkFunc.
+	Ignore []string
+	// Include — дополнительный фильтр записей вне архивов.
+	Include func(rel string, isDir bool) bool
+	// Archives, если задан, открывает zip и tar как директории.
+	Archives *archiveSet
+}
+
+// walkEntry — запись, которую обход передаёт в walkFunc.
+type walkEntry struct {
+	fsys fs.FS
+	// name — путь внутри fsys.
+	name string
+	// rel — путь от корня сканирования со слешами: по нему проверяются
+	// исключения и шаблоны поиска. Внутри архива это "bundle.zip!/src/main.go".
+	rel string
+	// path — путь для вывода пользователю.
+	path string
+	info fs.FileInfo
+	// inArchive — запись находится внутри архива.
+	inArchive bool
+}
+
+func (e walkEntry) isRoot() bool {
+	return e.rel == "."
+}
+
+func (e walkEntry) open() (fs.File, error) {
+	return e.fsys.Open(e.name)
+}
+
+type walkFunc func(e walkEntry, err error) error
+
+// walk работает как fs.WalkDir, но умеет ходить по символическим ссылкам
+// и внутрь архивов, а ошибка доступа к самому корню возвращается сразу,
+// без вызова fn. При Follow в fn передаётся информация о цели ссылки; битые
+// ссылки передаются как есть. При отмене ctx обход прерывается с ошибкой ctx.Err().
+func walk(ctx context.Context, target scanTarget, opts walkOptions, fn walkFunc) error {
+	return walkFrom(ctx, target, ".", opts, fn)
+}
+
+// walkFrom обходит только поддерево rel (путь со слешами от корня target).
+func walkFrom(ctx context.Context, target scanTarget, rel string, opts walkOptions, fn walkFunc) error {
+	root := walkEntry{fsys: target.fsys, name: rel, rel: rel, path: target.display(rel)}
+	info, err := lstat(target.fsys, root.name)
+	if err != nil {
+		// Недоступный корень — это ошибка всей операции, а не отдельной записи.
+		return err
+	}
+	root.info = info
+	if opts.Follow {
+		root.info = followLink(root)
+	}
+
+	w := &walker{ctx: ctx, target: target, opts: opts, fn: fn, ancestors: make(map[fileID]bool)}
+	err = w.walk(root)
+	if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+// followLink возвращает информацию о цели ссылки или саму ссылку, если она битая.
+func followLink(e walkEntry) fs.FileInfo {
+	if e.info.Mode()&fs.ModeSymlink == 0 {
+		return e.info
+	}
+	if target, err := fs.Stat(e.fsys, e.name); err == nil {
+		return target
+	}
+	return e.info
+}
+
+type walker struct {
+	ctx       context.Context
+	target    scanTarget
+	opts      walkOptions
+	fn        walkFunc
+	ancestors map[fileID]bool
+}
+
+func (w *walker) walk(e walkEntry) error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	if !e.isRoot() && utils.IsIgnored(e.rel, w.opts.Ignore, e.info.IsDir()) {
+		return nil
+	}
+	if !e.isRoot() && w.opts.Include != nil && !e.inArchive && !w.opts.Include(e.rel, e.info.IsDir()) {
+		return nil
+	}
+	if !e.info.IsDir() {
+		if err := w.fn(e, nil); err != nil {
+			return err
+		}
+		return w.walkArchive(e)
+	}
+
+	if w.opts.Follow {
+		if id, ok := fileKey(e); ok {
+			if w.ancestors[id] {
+				// Ссылка указывает на одного из предков: в директорию не заходим.
+				return w.fn(e, nil)
+			}
+			w.ancestors[id] = true
+			defer delete(w.ancestors, id)
+		}
+	}
+
+	entries, err := fs.ReadDir(e.fsys, e.name)
+	err1 := w.fn(e, err)
+	if err != nil || err1 != nil {
+		return err1
+	}
+	return w.walkEntries(e.fsys, e.name, e.rel, e.inArchive, entries)
+}
+
+// walkEntries обходит содержимое директории dir файловой системы fsys;
+// rel — путь этой директории от корня сканирования, inArchive — лежит ли она в архиве.
+func (w *walker) walkEntries(fsys fs.FS, dir, rel string, inArchive bool, entries []fs.DirEntry) error {
+	for _, entry := range entries {
+		name := path.Join(dir, entry.Name())
+		childRel := path.Join(rel, entry.Name())
+		child := walkEntry{fsys: fsys, name: name, rel: childRel, path: w.target.display(childRel), inArchive: inArchive}
+		info, err := entry.Info()
+		if err != nil {
+			if err := w.fn(child, err); err != nil && !errors.Is(err, fs.SkipDir) {
+				return err
+			}
+			continue
+		}
+		child.info = info
+		if w.opts.Follow {
+			child.info = followLink(child)
+		}
+
+		if err := w.walk(child); err != nil {
+			if !child.info.IsDir() || !errors.Is(err, fs.SkipDir) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// walkArchive обходит содержимое архива e как поддиректорию "e!".
+// Сам архив в fn уже передан как обычный файл.
+func (w *walker) walkArchive(e walkEntry) error {
+	if w.opts.Archives == nil || !isArchive(e.name) || !e.info.Mode().IsRegular() {
+		return nil
+	}
+
+	fsys, done, err := w.opts.Archives.open(e)
+	var entries []fs.DirEntry
+	if err == nil {
+		defer done()
+		entries, err = fs.ReadDir(fsys, ".")
+	}
+	if err != nil {
+		err = &fs.PathError{Op: "open archive", Path: e.path, Err: err}
+		if err := w.fn(e, err); err != nil && !errors.Is(err, fs.SkipDir) {
+			return err
+		}
+		return nil
+	}
+	return w.walkEntries(fsys, ".", e.rel+archiveSeparator, true, entries)
+}
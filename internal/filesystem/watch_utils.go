@@ -0,0 +1,243 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SHCDevelops/file-manager/internal/watch"
+)
+
+// watchDebounce — сколько копить события перед применением изменений:
+// сборка обычно меняет много файлов подряд.
+const watchDebounce = 300 * time.Millisecond
+
+// treeIndex — результат анализа, который обновляется по отдельным записям.
+type treeIndex interface {
+	// update учитывает файл (директории в index не передаются).
+	update(e walkEntry) error
+	// remove забывает запись rel и всё, что внутри неё (включая содержимое архива).
+	remove(rel string)
+	reset()
+}
+
+// WatchSpace сканирует dir как AnalyzeSpace, затем следит за изменениями и после
+// каждой порции изменений вызывает fn с новым списком top самых больших файлов.
+// Пересканируются только изменившиеся файлы и директории. Работает до отмены ctx
+// и возвращает ctx.Err(); пропущенные записи передаются в ScanOptions.OnError.
+func WatchSpace(ctx context.Context, dir string, top int, opts ScanOptions, fn func([]FileSize)) error {
+	index := &spaceIndex{files: make(map[string]FileSize)}
+	return watchTree(ctx, dir, opts, index, func() {
+		fn(index.top(top))
+	})
+}
+
+// WatchCodeStats работает как WatchSpace для CountCodeLines: после изменений
+// заново разбираются только изменившиеся файлы.
+func WatchCodeStats(ctx context.Context, dir string, ignoreLanguages []string, opts ScanOptions, fn func(*CodeStats)) error {
+	index := &codeIndex{files: make(map[string]FileStat), ignored: make(map[string]bool), opts: opts}
+	for _, lang := range ignoreLanguages {
+		index.ignored[strings.ToLower(lang)] = true
+	}
+	return watchTree(ctx, dir, opts, index, func() {
+		fn(index.stats())
+	})
+}
+
+func watchTree(ctx context.Context, dir string, opts ScanOptions, index treeIndex, emit func()) error {
+	w, err := watch.New()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	target := hostTarget(dir)
+	errs := newErrorCollector(opts)
+	scan := func(rel string) error {
+		walkOpts, archives := opts.walkOptions()
+		defer archives.close()
+		err := walkFrom(ctx, target, rel, walkOpts, func(e walkEntry, err error) error {
+			if err != nil {
+				return errs.add(e.path, "walk", err)
+			}
+			if !e.info.IsDir() {
+				opts.Progress.AddFiles(1)
+				if err := index.update(e); err != nil {
+					return errs.add(e.path, "read", err)
+				}
+				return nil
+			}
+			if e.inArchive {
+				// Директория внутри архива: следить не за чем.
+				return nil
+			}
+			if err := w.Add(e.path); err != nil {
+				return errs.add(e.path, "watch", err)
+			}
+			return nil
+		})
+		if errors.Is(err, fs.ErrNotExist) && rel != "." {
+			// Запись успела исчезнуть: её удаление придёт отдельным событием.
+			return nil
+		}
+		return err
+	}
+
+	opts.Progress.SetPhase("scanning", 0)
+	if err := scan("."); err != nil {
+		return err
+	}
+	emit()
+
+	pending := make(map[string]bool)
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-w.Errors():
+			return err
+		case event, ok := <-w.Events():
+			if !ok {
+				return errors.New("watch stopped unexpectedly")
+			}
+			if len(pending) == 0 {
+				// Таймер не продлевается: при непрерывной записи
+				// результат всё равно обновляется каждые watchDebounce.
+				timer.Reset(watchDebounce)
+			}
+			if event.Op == watch.Overflow {
+				pending = map[string]bool{".": true}
+			} else if rel, err := filepath.Rel(dir, event.Path); err == nil && !pending["."] {
+				pending[filepath.ToSlash(rel)] = true
+			}
+		case <-timer.C:
+			if err := applyChanges(dir, pending, index, w, scan); err != nil {
+				return err
+			}
+			pending = make(map[string]bool)
+			emit()
+		}
+	}
+}
+
+// applyChanges заново сканирует изменившиеся пути. Сначала всё забывается,
+// затем существующие пути сканируются: так одинаково обрабатываются создание,
+// изменение, удаление и перемещение файлов и директорий.
+func applyChanges(dir string, pending map[string]bool, index treeIndex, w *watch.Watcher, scan func(rel string) error) error {
+	if pending["."] {
+		index.reset()
+		w.RemoveTree(dir)
+		return scan(".")
+	}
+
+	paths := make([]string, 0, len(pending))
+	for rel := range pending {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+
+	for _, rel := range paths {
+		index.remove(rel)
+		w.RemoveTree(filepath.Join(dir, filepath.FromSlash(rel)))
+	}
+	for i, rel := range paths {
+		// Вложенный путь уже отсканирован вместе с родителем.
+		if i > 0 && within(rel, paths[i-1]) {
+			paths[i] = paths[i-1]
+			continue
+		}
+		if err := scan(rel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// within сообщает, что rel совпадает с parent или лежит внутри него
+// (в том числе внутри архива parent).
+func within(rel, parent string) bool {
+	return rel == parent || strings.HasPrefix(rel, parent+"/") || strings.HasPrefix(rel, parent+archiveSeparator+"/")
+}
+
+type spaceIndex struct {
+	files map[string]FileSize
+}
+
+func (x *spaceIndex) update(e walkEntry) error {
+	x.files[e.rel] = FileSize{Path: e.path, Size: e.info.Size()}
+	return nil
+}
+
+func (x *spaceIndex) remove(rel string) {
+	for path := range x.files {
+		if within(path, rel) {
+			delete(x.files, path)
+		}
+	}
+}
+
+func (x *spaceIndex) reset() {
+	x.files = make(map[string]FileSize)
+}
+
+func (x *spaceIndex) top(n int) []FileSize {
+	files := make([]FileSize, 0, len(x.files))
+	for _, f := range x.files {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Size != files[j].Size {
+			return files[i].Size > files[j].Size
+		}
+		return files[i].Path < files[j].Path
+	})
+	if len(files) > n {
+		files = files[:n]
+	}
+	return files
+}
+
+type codeIndex struct {
+	files   map[string]FileStat
+	ignored map[string]bool
+	opts    ScanOptions
+}
+
+func (x *codeIndex) update(e walkEntry) error {
+	lang := getLanguage(e.path, x.ignored)
+	if lang == "" {
+		return nil
+	}
+	file, err := analyzeFile(e.fsys, e.name, e.rel, lang, x.opts)
+	if err != nil {
+		return err
+	}
+	x.files[e.rel] = file.withoutLanguages(x.ignored)
+	return nil
+}
+
+func (x *codeIndex) remove(rel string) {
+	for path := range x.files {
+		if within(path, rel) {
+			delete(x.files, path)
+		}
+	}
+}
+
+func (x *codeIndex) reset() {
+	x.files = make(map[string]FileStat)
+}
+
+func (x *codeIndex) stats() *CodeStats {
+	stats := newCodeStats()
+	for rel, f := range x.files {
+		stats.add(rel, f, x.opts.countsClass(f.Class))
+	}
+	return stats
+}
@@ -0,0 +1,87 @@
+// Package git читает данные репозитория через локальный бинарник git.
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// run выполняет git в директории dir и возвращает stdout.
+func run(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, errors.New("git is not installed or not in PATH")
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
+
+// Tracked — файлы, которые есть в индексе репозитория.
+type Tracked struct {
+	files map[string]bool
+	dirs  map[string]bool
+}
+
+// TrackedFiles возвращает файлы индекса внутри dir; пути — относительно dir со слешами.
+func TrackedFiles(ctx context.Context, dir string) (*Tracked, error) {
+	out, err := run(ctx, dir, "ls-files", "-z", "--cached")
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tracked{files: make(map[string]bool), dirs: make(map[string]bool)}
+	for _, name := range strings.Split(string(out), "\x00") {
+		if name == "" {
+			continue
+		}
+		t.files[name] = true
+		for d := path.Dir(name); d != "." && !t.dirs[d]; d = path.Dir(d) {
+			t.dirs[d] = true
+		}
+	}
+	return t, nil
+}
+
+// Include подходит для ScanOptions.Include: директории пропускаются,
+// если в них нет ни одного файла из индекса.
+func (t *Tracked) Include(name string, isDir bool) bool {
+	if isDir {
+		return t.dirs[name]
+	}
+	return t.files[name]
+}
+
+// Blame возвращает количество строк файла по авторам последних изменений.
+// Незакоммиченные строки приписываются автору "Not Committed Yet".
+func Blame(ctx context.Context, dir, file string) (map[string]int, error) {
+	out, err := run(ctx, dir, "blame", "--line-porcelain", "--", file)
+	if err != nil {
+		return nil, err
+	}
+
+	authors := make(map[string]int)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		// Строки содержимого начинаются с табуляции, так что "author "
+		// в начале строки — всегда заголовок.
+		if author, ok := strings.CutPrefix(scanner.Text(), "author "); ok {
+			authors[author]++
+		}
+	}
+	return authors, scanner.Err()
+}
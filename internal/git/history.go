@@ -0,0 +1,158 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Commit — коммит из истории репозитория.
+type Commit struct {
+	Hash    string
+	Date    time.Time
+	Subject string
+}
+
+// LogOptions ограничивает список коммитов для Log.
+type LogOptions struct {
+	// Range — диапазон ревизий в синтаксисе git ("v1.0..HEAD"); по умолчанию HEAD.
+	Range string
+	// Since — дата в любом формате, который понимает git ("2024-01-01", "6 months ago").
+	Since string
+	// MaxCount оставляет только последние MaxCount коммитов (0 — без ограничения).
+	MaxCount int
+}
+
+// Log возвращает коммиты, затрагивающие dir, от старых к новым. Учитывается
+// только первый родитель: ветки, влитые merge-коммитом, видны одной точкой.
+func Log(ctx context.Context, dir string, opts LogOptions) ([]Commit, error) {
+	args := []string{"log", "--first-parent", "--reverse", "-z", "--format=%H%x00%cI%x00%s"}
+	if opts.Since != "" {
+		args = append(args, "--since="+opts.Since)
+	}
+	if opts.MaxCount > 0 {
+		args = append(args, "--max-count="+strconv.Itoa(opts.MaxCount))
+	}
+	rng := opts.Range
+	if rng == "" {
+		rng = "HEAD"
+	}
+	if strings.HasPrefix(rng, "-") {
+		return nil, fmt.Errorf("invalid revision range %q", rng)
+	}
+	args = append(args, rng, "--", ".")
+
+	out, err := run(ctx, dir, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// С -z записи разделены NUL, как и поля внутри записи.
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	if len(fields) < 3 {
+		return nil, nil
+	}
+	commits := make([]Commit, 0, len(fields)/3)
+	for i := 0; i+2 < len(fields); i += 3 {
+		date, err := time.Parse(time.RFC3339, fields[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("git log: bad date %q: %w", fields[i+1], err)
+		}
+		commits = append(commits, Commit{Hash: fields[i], Date: date, Subject: fields[i+2]})
+	}
+	return commits, nil
+}
+
+// TreeEntry — файл в дереве коммита.
+type TreeEntry struct {
+	// Path — путь относительно dir со слешами.
+	Path string
+	// Blob — идентификатор содержимого: у одинаковых файлов он совпадает.
+	Blob string
+}
+
+// Tree возвращает обычные файлы коммита внутри dir. Символические ссылки
+// и подмодули пропускаются.
+func Tree(ctx context.Context, dir, commit string) ([]TreeEntry, error) {
+	out, err := run(ctx, dir, "ls-tree", "-r", "-z", commit)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []TreeEntry
+	for _, line := range strings.Split(string(out), "\x00") {
+		// "<mode> <type> <object>\t<path>"
+		meta, name, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		parts := strings.Fields(meta)
+		if len(parts) != 3 || parts[1] != "blob" || parts[0] == "120000" {
+			continue
+		}
+		entries = append(entries, TreeEntry{Path: name, Blob: parts[2]})
+	}
+	return entries, nil
+}
+
+// BlobReader читает содержимое объектов через один долгоживущий процесс
+// git cat-file --batch, чтобы не запускать git для каждого файла.
+// Методы нельзя вызывать из нескольких горутин одновременно.
+type BlobReader struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+func NewBlobReader(ctx context.Context, dir string) (*BlobReader, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "cat-file", "--batch")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	return &BlobReader{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}, nil
+}
+
+// Read возвращает содержимое объекта blob.
+func (r *BlobReader) Read(blob string) ([]byte, error) {
+	if _, err := io.WriteString(r.stdin, blob+"\n"); err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	header, err := r.stdout.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	// "<object> <type> <size>" или "<object> missing"
+	parts := strings.Fields(header)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("git cat-file: object %s not found", blob)
+	}
+	size, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: bad header %q", strings.TrimSpace(header))
+	}
+
+	data := make([]byte, size+1)
+	if _, err := io.ReadFull(r.stdout, data); err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	// Последний байт — перевод строки после содержимого.
+	return data[:size], nil
+}
+
+func (r *BlobReader) Close() error {
+	r.stdin.Close()
+	return r.cmd.Wait()
+}
@@ -0,0 +1,118 @@
+package progress
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Counter накапливает прогресс сканирования. Методы безопасны для
+// конкурентного вызова и для nil-указателя, поэтому сканеры могут
+// обновлять счётчики без проверок.
+type Counter struct {
+	files      atomic.Int64
+	bytes      atomic.Int64
+	totalBytes atomic.Int64
+
+	mu         sync.Mutex
+	phase      string
+	phaseStart time.Time
+	start      time.Time
+}
+
+func NewCounter() *Counter {
+	now := time.Now()
+	return &Counter{start: now, phaseStart: now}
+}
+
+// SetPhase переключает фазу (например, "scanning" или "hashing") и сбрасывает
+// счётчики байт, чтобы скорость и ETA считались для новой фазы.
+func (c *Counter) SetPhase(phase string, totalBytes int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.phase = phase
+	c.phaseStart = time.Now()
+	c.bytes.Store(0)
+	c.totalBytes.Store(totalBytes)
+}
+
+func (c *Counter) AddFiles(n int64) {
+	if c == nil {
+		return
+	}
+	c.files.Add(n)
+}
+
+func (c *Counter) AddBytes(n int64) {
+	if c == nil {
+		return
+	}
+	c.bytes.Add(n)
+}
+
+type Snapshot struct {
+	Phase      string
+	Files      int64
+	Bytes      int64
+	TotalBytes int64
+	Elapsed    time.Duration
+	// Throughput — байт в секунду в текущей фазе.
+	Throughput float64
+	// ETA известен, только если задан объём фазы (TotalBytes > 0).
+	ETA time.Duration
+}
+
+func (c *Counter) Snapshot() Snapshot {
+	c.mu.Lock()
+	phase, phaseStart, start := c.phase, c.phaseStart, c.start
+	c.mu.Unlock()
+
+	now := time.Now()
+	s := Snapshot{
+		Phase:      phase,
+		Files:      c.files.Load(),
+		Bytes:      c.bytes.Load(),
+		TotalBytes: c.totalBytes.Load(),
+		Elapsed:    now.Sub(start),
+	}
+
+	if phaseElapsed := now.Sub(phaseStart).Seconds(); phaseElapsed > 0 {
+		s.Throughput = float64(s.Bytes) / phaseElapsed
+	}
+	if s.TotalBytes > 0 && s.Throughput > 0 && s.Bytes < s.TotalBytes {
+		s.ETA = time.Duration(float64(s.TotalBytes-s.Bytes) / s.Throughput * float64(time.Second))
+	}
+	return s
+}
+
+// Watch вызывает fn со снимком прогресса каждые interval, пока не будет
+// вызвана возвращённая функция.
+func Watch(c *Counter, interval time.Duration, fn func(Snapshot)) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				fn(c.Snapshot())
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/SHCDevelops/file-manager/lib/utils"
+	"github.com/mattn/go-isatty"
+)
+
+const (
+	ttyInterval = 200 * time.Millisecond
+	logInterval = 5 * time.Second
+)
+
+// IsTerminal сообщает, подключён ли w к терминалу.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && (isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd()))
+}
+
+// Renderer печатает снимки прогресса. На терминале это перерисовываемая
+// строка состояния, иначе — обычные строки лога.
+type Renderer struct {
+	mu  sync.Mutex
+	w   io.Writer
+	tty bool
+}
+
+func NewRenderer(w io.Writer) *Renderer {
+	return &Renderer{w: w, tty: IsTerminal(w)}
+}
+
+// NewStatusLineRenderer работает как NewRenderer, но перерисовывает строку
+// состояния, даже если w не терминал.
+func NewStatusLineRenderer(w io.Writer) *Renderer {
+	return &Renderer{w: w, tty: true}
+}
+
+// Interval — рекомендуемый период обновления: часто на терминале и редко в логах.
+func (r *Renderer) Interval() time.Duration {
+	if r.tty {
+		return ttyInterval
+	}
+	return logInterval
+}
+
+func (r *Renderer) Update(s Snapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tty {
+		fmt.Fprintf(r.w, "\r\033[K%s", Format(s))
+	} else {
+		fmt.Fprintf(r.w, "[%s] %s\n", time.Now().Format(time.TimeOnly), Format(s))
+	}
+}
+
+// Close стирает строку состояния, чтобы она не смешивалась с результатами.
+func (r *Renderer) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tty {
+		fmt.Fprint(r.w, "\r\033[K")
+	}
+}
+
+// Format описывает снимок одной строкой.
+func Format(s Snapshot) string {
+	line := fmt.Sprintf("%s: %d files", phaseName(s.Phase), s.Files)
+	if s.Bytes > 0 || s.TotalBytes > 0 {
+		line += ", " + utils.FormatSize(s.Bytes)
+		if s.TotalBytes > 0 {
+			line += fmt.Sprintf(" of %s (%.0f%%)", utils.FormatSize(s.TotalBytes), float64(s.Bytes)*100/float64(s.TotalBytes))
+		}
+		line += fmt.Sprintf(", %s/s", utils.FormatSize(int64(s.Throughput)))
+	}
+	if s.ETA > 0 {
+		line += ", ETA " + s.ETA.Round(time.Second).String()
+	}
+	return line + ", elapsed " + s.Elapsed.Round(time.Second).String()
+}
+
+func phaseName(phase string) string {
+	if phase == "" {
+		return "scanning"
+	}
+	return phase
+}
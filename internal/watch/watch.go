@@ -0,0 +1,27 @@
+// Package watch сообщает об изменениях в директориях.
+package watch
+
+import "errors"
+
+// ErrUnsupported возвращается New на платформах без inotify.
+var ErrUnsupported = errors.New("watch mode is only supported on Linux")
+
+type Op uint32
+
+const (
+	// Create — запись появилась (создана или перемещена внутрь).
+	Create Op = 1 << iota
+	// Write — содержимое или метаданные записи изменились.
+	Write
+	// Remove — запись удалена или перемещена наружу.
+	Remove
+	// Overflow — очередь событий переполнилась и часть изменений потеряна:
+	// состояние нужно пересканировать целиком.
+	Overflow
+)
+
+type Event struct {
+	Path  string
+	Op    Op
+	IsDir bool
+}
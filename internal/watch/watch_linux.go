@@ -0,0 +1,164 @@
+//go:build linux
+
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const watchMask = unix.IN_CREATE | unix.IN_CLOSE_WRITE | unix.IN_MODIFY | unix.IN_ATTRIB |
+	unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_ONLYDIR
+
+// Watcher следит за отдельными директориями (без вложенных) через inotify.
+type Watcher struct {
+	file   *os.File
+	fd     int
+	events chan Event
+	errors chan error
+	// done закрывается в Close, чтобы read не зависал на отправке события,
+	// которое уже никто не прочитает.
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu    sync.Mutex
+	paths map[int]string
+	wds   map[string]int
+}
+
+func New() (*Watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	w := &Watcher{
+		// Неблокирующий дескриптор попадает в сетевой поллер Go,
+		// поэтому Close прерывает ожидающее чтение.
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		fd:     fd,
+		events: make(chan Event, 256),
+		errors: make(chan error, 1),
+		done:   make(chan struct{}),
+		paths:  make(map[int]string),
+		wds:    make(map[string]int),
+	}
+	go w.read()
+	return w, nil
+}
+
+func (w *Watcher) Events() <-chan Event { return w.events }
+
+// Errors получает ошибку чтения событий; после неё Events закрывается.
+func (w *Watcher) Errors() <-chan error { return w.errors }
+
+// Add начинает следить за директорией dir.
+func (w *Watcher) Add(dir string) error {
+	wd, err := unix.InotifyAddWatch(w.fd, dir, watchMask)
+	if err != nil {
+		if errors.Is(err, unix.ENOSPC) {
+			return fmt.Errorf("inotify watch limit reached (see fs.inotify.max_user_watches): %w", err)
+		}
+		return &os.PathError{Op: "watch", Path: dir, Err: err}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if old, ok := w.paths[wd]; ok {
+		delete(w.wds, old)
+	}
+	w.paths[wd] = dir
+	w.wds[dir] = wd
+	return nil
+}
+
+// RemoveTree перестаёт следить за dir и всеми директориями внутри неё.
+func (w *Watcher) RemoveTree(dir string) {
+	prefix := dir + string(filepath.Separator)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for path, wd := range w.wds {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			unix.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.wds, path)
+			delete(w.paths, wd)
+		}
+	}
+}
+
+func (w *Watcher) Close() error {
+	w.closeOnce.Do(func() { close(w.done) })
+	return w.file.Close()
+}
+
+func (w *Watcher) read() {
+	defer close(w.events)
+
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				w.errors <- err
+			}
+			return
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(raw.Len)]
+			offset += unix.SizeofInotifyEvent + int(raw.Len)
+
+			if event, ok := w.translate(raw, strings.TrimRight(string(nameBytes), "\x00")); ok {
+				select {
+				case w.events <- event:
+				case <-w.done:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (w *Watcher) translate(raw *unix.InotifyEvent, name string) (Event, bool) {
+	mask := raw.Mask
+	if mask&unix.IN_Q_OVERFLOW != 0 {
+		return Event{Op: Overflow}, true
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	dir, ok := w.paths[int(raw.Wd)]
+	if !ok {
+		return Event{}, false
+	}
+	if mask&unix.IN_IGNORED != 0 {
+		// Директория удалена: ядро уже сняло наблюдение.
+		delete(w.paths, int(raw.Wd))
+		if w.wds[dir] == int(raw.Wd) {
+			delete(w.wds, dir)
+		}
+		return Event{}, false
+	}
+	if name == "" {
+		return Event{}, false
+	}
+
+	event := Event{Path: filepath.Join(dir, name), IsDir: mask&unix.IN_ISDIR != 0}
+	switch {
+	case mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0:
+		event.Op = Create
+	case mask&(unix.IN_DELETE|unix.IN_MOVED_FROM) != 0:
+		event.Op = Remove
+	default:
+		event.Op = Write
+	}
+	return event, true
+}
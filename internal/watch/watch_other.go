@@ -0,0 +1,15 @@
+//go:build !linux
+
+package watch
+
+type Watcher struct{}
+
+func New() (*Watcher, error) {
+	return nil, ErrUnsupported
+}
+
+func (w *Watcher) Events() <-chan Event  { return nil }
+func (w *Watcher) Errors() <-chan error  { return nil }
+func (w *Watcher) Add(dir string) error  { return ErrUnsupported }
+func (w *Watcher) RemoveTree(dir string) {}
+func (w *Watcher) Close() error          { return nil }
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var sizeUnits = map[string]int64{
+	"":  1,
+	"b": 1,
+	"c": 1,
+	"k": 1 << 10,
+	"m": 1 << 20,
+	"g": 1 << 30,
+	"t": 1 << 40,
+}
+
+// ParseSize разбирает размер вида 512, 10k, 10M, 1G (множитель 1024).
+// Суффиксы B и iB допускаются: 10MB, 10MiB.
+func ParseSize(s string) (int64, error) {
+	value, unit, err := SplitSize(s)
+	if err != nil {
+		return 0, err
+	}
+	return value * unit, nil
+}
+
+// SplitSize возвращает число и множитель единицы измерения по отдельности.
+func SplitSize(s string) (value int64, unit int64, err error) {
+	str := strings.ToLower(strings.TrimSpace(s))
+	str = strings.TrimSuffix(str, "ib")
+	if len(str) > 1 {
+		str = strings.TrimSuffix(str, "b")
+	}
+
+	i := len(str)
+	for i > 0 && (str[i-1] < '0' || str[i-1] > '9') {
+		i--
+	}
+
+	unit, ok := sizeUnits[str[i:]]
+	if !ok || i == 0 {
+		return 0, 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	value, err = strconv.ParseInt(str[:i], 10, 64)
+	if err != nil || value < 0 {
+		return 0, 0, fmt.Errorf("invalid size %q", s)
+	}
+	return value, unit, nil
+}
+
+// FormatSize выводит размер в двоичных единицах: 512 B, 1.5 KiB, 10.0 MiB.
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
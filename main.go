@@ -1,26 +1,86 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/SHCDevelops/file-manager/cmd"
+	"github.com/SHCDevelops/file-manager/internal/config"
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"os"
+	"os/signal"
 )
 
 func main() {
+	// Первый Ctrl-C отменяет сканирование и команда печатает частичный результат,
+	// второй — завершает процесс сразу.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	cancelTimeout := context.CancelFunc(func() {})
+
 	rootCmd := &cobra.Command{
 		Use:   "file-manager",
 		Short: "CLI tool for managing and analyzing files",
-		Long:  `File Manager is a powerful CLI tool to analyze and manage files and directories.`,
+		Long: `File Manager is a powerful CLI tool to analyze and manage files and directories.
+
+Flag defaults can be set in .file-manager.yaml (searched in the current and parent
+directories) and $XDG_CONFIG_HOME/file-manager/config.yaml; flags given on the command
+line always win.
+
+Exit codes: 0 on success, 1 when a --fail-* policy check triggers, 2 on errors.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(c *cobra.Command, args []string) error {
+			configPath, _ := c.Flags().GetString("config")
+			profile, _ := c.Flags().GetString("profile")
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+			if err := cfg.Apply(c, profile); err != nil {
+				return err
+			}
+
+			timeout, err := c.Flags().GetDuration("timeout")
+			if err != nil {
+				return err
+			}
+			if timeout > 0 {
+				var ctx context.Context
+				ctx, cancelTimeout = context.WithTimeout(c.Context(), timeout)
+				c.SetContext(ctx)
+			}
+			return nil
+		},
 	}
+	rootCmd.PersistentFlags().String("config", "", "Config file to use instead of "+config.ProjectFile+" found in the current or a parent directory")
+	rootCmd.PersistentFlags().String("profile", "", "Apply the named profile from the config files")
+	rootCmd.PersistentFlags().Duration("timeout", 0, "Stop scanning after the given duration and show partial results (e.g., 30s, 5m)")
 
 	rootCmd.AddCommand(cmd.AnalyzeSpaceCmd)
 	rootCmd.AddCommand(cmd.FindDuplicatesCmd)
+	rootCmd.AddCommand(cmd.FindClonesCmd)
 	rootCmd.AddCommand(cmd.SearchCmd)
 	rootCmd.AddCommand(cmd.CodeStatsCmd)
+	rootCmd.AddCommand(cmd.SnapshotCmd)
+	rootCmd.AddCommand(cmd.DiffCmd)
+	rootCmd.AddCommand(cmd.TodosCmd)
+	rootCmd.AddCommand(cmd.LicensesCmd)
 
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	err := rootCmd.ExecuteContext(ctx)
+	cancelTimeout()
+	stop()
+	if err != nil {
+		if errors.Is(err, cmd.ErrFindings) {
+			fmt.Fprintln(os.Stderr, color.YellowString("%v", err))
+		} else {
+			fmt.Fprintln(os.Stderr, color.RedString("Error: %v", err))
+		}
+		os.Exit(cmd.ExitCode(err))
 	}
 }
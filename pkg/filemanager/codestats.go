@@ -0,0 +1,310 @@
+package filemanager
+
+import (
+	"time"
+
+	"github.com/SHCDevelops/file-manager/internal/filesystem"
+	"github.com/SHCDevelops/file-manager/internal/git"
+)
+
+// Типы статистики кода принадлежат пакету, а не повторяют внутренние:
+// новые поля internal/filesystem не меняют публичный API сами по себе.
+
+type FileClass = filesystem.FileClass
+
+const (
+	ClassSource    = filesystem.ClassSource
+	ClassGenerated = filesystem.ClassGenerated
+	ClassVendored  = filesystem.ClassVendored
+)
+
+// LanguageStat — строки и метрики сложности языка, файла или класса файлов.
+type LanguageStat struct {
+	TotalLines   int
+	CommentLines int
+	BlankLines   int
+	// CodeLines — строки, которые не являются ни комментариями, ни пустыми.
+	CodeLines int
+
+	// Метрики сложности заполняются только с WithComplexity. Для Go они точные,
+	// для остальных языков — оценка по ключевым словам и отступам.
+	Functions     int
+	Branches      int
+	Complexity    int
+	MaxComplexity int
+	MaxNesting    int
+	// Indentation — число строк кода по уровню отступа; последний элемент
+	// включает все более глубокие уровни.
+	Indentation []int
+}
+
+type FileStat struct {
+	Language string
+	Class    FileClass
+	LanguageStat
+	// FunctionStats заполняется только для Go с WithComplexity.
+	FunctionStats []FunctionStat
+	// Embedded — фрагменты на других языках (<script> в HTML, блоки кода
+	// в Markdown); их строки не входят в LanguageStat файла.
+	Embedded map[string]*LanguageStat
+}
+
+type ClassStat struct {
+	Files int
+	LanguageStat
+}
+
+type CodeStats struct {
+	Languages map[string]*LanguageStat
+	// Files — статистика по файлам; ключ — путь от корня сканирования со слешами.
+	Files map[string]*FileStat
+	// Excluded — сгенерированные и сторонние файлы, не вошедшие в Languages и Files.
+	Excluded map[FileClass]*ClassStat
+}
+
+// FunctionStat — метрики одной функции или метода Go.
+type FunctionStat struct {
+	Name       string
+	Path       string
+	Line       int
+	Complexity int
+	Nesting    int
+}
+
+// FileComplexity — файл с его оценкой сложности.
+type FileComplexity struct {
+	Path string
+	*FileStat
+}
+
+// TopFunctions возвращает n самых сложных функций по всем файлам.
+func (s *CodeStats) TopFunctions(n int) []FunctionStat {
+	top := s.internal().TopFunctions(n)
+	functions := make([]FunctionStat, len(top))
+	for i, fn := range top {
+		functions[i] = FunctionStat(fn)
+	}
+	return functions
+}
+
+// TopFiles возвращает n файлов с наибольшей сложностью по всем языкам.
+func (s *CodeStats) TopFiles(n int) []FileComplexity {
+	top := s.internal().TopFiles(n)
+	files := make([]FileComplexity, len(top))
+	for i, f := range top {
+		files[i] = FileComplexity{Path: f.Path, FileStat: s.Files[f.Path]}
+	}
+	return files
+}
+
+// AuthorStat — строки, последним изменением которых владеет автор.
+type AuthorStat struct {
+	Author    string
+	Lines     int
+	Languages map[string]int
+}
+
+// Revision — статистика кода в дереве одного коммита.
+type Revision struct {
+	Commit    string
+	Date      time.Time
+	Subject   string
+	Languages map[string]*LanguageStat
+}
+
+// HistoryOptions выбирает коммиты для CodeStatsHistory.
+type HistoryOptions struct {
+	// Range — диапазон ревизий в синтаксисе git ("v1.0..HEAD"); по умолчанию HEAD.
+	Range string
+	// Since — дата в любом формате, который понимает git ("2024-01-01", "6 months ago").
+	Since string
+	// MaxCount оставляет только последние MaxCount коммитов (0 — без ограничения).
+	MaxCount int
+}
+
+// LanguageDelta — изменение статистики языка или файла между двумя деревьями.
+type LanguageDelta struct {
+	Language string
+	Old, New LanguageStat
+}
+
+// Delta возвращает New - Old по числу строк.
+func (d LanguageDelta) Delta() LanguageStat {
+	return LanguageStat{
+		TotalLines:   d.New.TotalLines - d.Old.TotalLines,
+		CommentLines: d.New.CommentLines - d.Old.CommentLines,
+		BlankLines:   d.New.BlankLines - d.Old.BlankLines,
+		CodeLines:    d.New.CodeLines - d.Old.CodeLines,
+	}
+}
+
+type FileDelta struct {
+	// Path — путь от корня сканирования со слешами.
+	Path string
+	LanguageDelta
+	// Added и Removed — файл есть только в новом или только в старом дереве.
+	Added, Removed bool
+	// Embedded — запись о фрагментах на языке Language внутри файла
+	// (<script> в HTML, блоки кода в Markdown), а не об основной части файла.
+	Embedded bool
+}
+
+type CodeStatsDiff struct {
+	// Languages и Files содержат только изменившиеся записи,
+	// по убыванию модуля изменения строк кода.
+	Languages []LanguageDelta
+	Files     []FileDelta
+}
+
+func newLanguageStat(s filesystem.LanguageStat) LanguageStat {
+	return LanguageStat{
+		TotalLines:    s.TotalLines,
+		CommentLines:  s.CommentLines,
+		BlankLines:    s.BlankLines,
+		CodeLines:     s.CodeLines,
+		Functions:     s.Functions,
+		Branches:      s.Branches,
+		Complexity:    s.Complexity,
+		MaxComplexity: s.MaxComplexity,
+		MaxNesting:    s.MaxNesting,
+		Indentation:   append([]int(nil), s.Indentation[:]...),
+	}
+}
+
+func (s LanguageStat) internal() filesystem.LanguageStat {
+	stat := filesystem.LanguageStat{
+		TotalLines:    s.TotalLines,
+		CommentLines:  s.CommentLines,
+		BlankLines:    s.BlankLines,
+		CodeLines:     s.CodeLines,
+		Functions:     s.Functions,
+		Branches:      s.Branches,
+		Complexity:    s.Complexity,
+		MaxComplexity: s.MaxComplexity,
+		MaxNesting:    s.MaxNesting,
+	}
+	copy(stat.Indentation[:], s.Indentation)
+	return stat
+}
+
+func newLanguages(languages map[string]*filesystem.LanguageStat) map[string]*LanguageStat {
+	if languages == nil {
+		return nil
+	}
+	result := make(map[string]*LanguageStat, len(languages))
+	for lang, stat := range languages {
+		converted := newLanguageStat(*stat)
+		result[lang] = &converted
+	}
+	return result
+}
+
+func internalLanguages(languages map[string]*LanguageStat) map[string]*filesystem.LanguageStat {
+	if languages == nil {
+		return nil
+	}
+	result := make(map[string]*filesystem.LanguageStat, len(languages))
+	for lang, stat := range languages {
+		converted := stat.internal()
+		result[lang] = &converted
+	}
+	return result
+}
+
+func newCodeStats(stats *filesystem.CodeStats) *CodeStats {
+	if stats == nil {
+		return nil
+	}
+	result := &CodeStats{
+		Languages: newLanguages(stats.Languages),
+		Files:     make(map[string]*FileStat, len(stats.Files)),
+		Excluded:  make(map[FileClass]*ClassStat, len(stats.Excluded)),
+	}
+	for rel, file := range stats.Files {
+		converted := &FileStat{
+			Language:     file.Language,
+			Class:        file.Class,
+			LanguageStat: newLanguageStat(file.LanguageStat),
+			Embedded:     newLanguages(file.Embedded),
+		}
+		for _, fn := range file.FunctionStats {
+			converted.FunctionStats = append(converted.FunctionStats, FunctionStat(fn))
+		}
+		result.Files[rel] = converted
+	}
+	for class, stat := range stats.Excluded {
+		result.Excluded[class] = &ClassStat{Files: stat.Files, LanguageStat: newLanguageStat(stat.LanguageStat)}
+	}
+	return result
+}
+
+// internal нужен, чтобы передать статистику, полученную от пакета (или
+// собранную вызывающим кодом), обратно в internal/filesystem. nil
+// превращается в пустую статистику.
+func (s *CodeStats) internal() *filesystem.CodeStats {
+	if s == nil {
+		s = &CodeStats{}
+	}
+	stats := &filesystem.CodeStats{
+		Languages: internalLanguages(s.Languages),
+		Files:     make(map[string]*filesystem.FileStat, len(s.Files)),
+		Excluded:  make(map[FileClass]*filesystem.ClassStat, len(s.Excluded)),
+	}
+	for rel, file := range s.Files {
+		converted := &filesystem.FileStat{
+			Language:     file.Language,
+			Class:        file.Class,
+			LanguageStat: file.LanguageStat.internal(),
+			Embedded:     internalLanguages(file.Embedded),
+		}
+		for _, fn := range file.FunctionStats {
+			converted.FunctionStats = append(converted.FunctionStats, filesystem.FunctionStat(fn))
+		}
+		stats.Files[rel] = converted
+	}
+	for class, stat := range s.Excluded {
+		stats.Excluded[class] = &filesystem.ClassStat{Files: stat.Files, LanguageStat: stat.LanguageStat.internal()}
+	}
+	return stats
+}
+
+func newAuthors(authors []filesystem.AuthorStat) []AuthorStat {
+	result := make([]AuthorStat, len(authors))
+	for i, author := range authors {
+		result[i] = AuthorStat(author)
+	}
+	return result
+}
+
+func newRevisions(revisions []filesystem.Revision) []Revision {
+	result := make([]Revision, len(revisions))
+	for i, rev := range revisions {
+		result[i] = Revision{Commit: rev.Commit, Date: rev.Date, Subject: rev.Subject, Languages: newLanguages(rev.Languages)}
+	}
+	return result
+}
+
+func (h HistoryOptions) internal() git.LogOptions {
+	return git.LogOptions(h)
+}
+
+func newLanguageDelta(d filesystem.LanguageDelta) LanguageDelta {
+	return LanguageDelta{Language: d.Language, Old: newLanguageStat(d.Old), New: newLanguageStat(d.New)}
+}
+
+func newCodeStatsDiff(diff *filesystem.CodeStatsDiff) *CodeStatsDiff {
+	result := &CodeStatsDiff{}
+	for _, d := range diff.Languages {
+		result.Languages = append(result.Languages, newLanguageDelta(d))
+	}
+	for _, f := range diff.Files {
+		result.Files = append(result.Files, FileDelta{
+			Path:          f.Path,
+			LanguageDelta: newLanguageDelta(f.LanguageDelta),
+			Added:         f.Added,
+			Removed:       f.Removed,
+			Embedded:      f.Embedded,
+		})
+	}
+	return result
+}
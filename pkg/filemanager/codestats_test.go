@@ -0,0 +1,69 @@
+package filemanager
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/SHCDevelops/file-manager/internal/filesystem"
+)
+
+func codeStatsFixture(t *testing.T) *filesystem.CodeStats {
+	t.Helper()
+	fsys := fstest.MapFS{
+		"main.go":           {Data: []byte("package main\n\nfunc main() {\n\tif true {\n\t\tfor {\n\t\t\tbreak\n\t\t}\n\t}\n}\n")},
+		"index.html":        {Data: []byte("<html>\n<script>\nfunction f() {\n  return 1;\n}\n</script>\n</html>\n")},
+		"vendor/lib/lib.go": {Data: []byte("package lib\n")},
+	}
+	stats, err := filesystem.CountCodeLinesFS(context.Background(), fsys, nil, filesystem.ScanOptions{Complexity: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stats
+}
+
+func TestCodeStatsRoundTrip(t *testing.T) {
+	stats := codeStatsFixture(t)
+	public := newCodeStats(stats)
+
+	main := public.Files["main.go"]
+	if main == nil || len(main.FunctionStats) != 1 || main.Indentation[2] == 0 {
+		t.Fatalf("main.go: got %+v, want one function and indented lines", main)
+	}
+	if html := public.Files["index.html"]; html == nil || html.Embedded["JavaScript"] == nil {
+		t.Fatalf("index.html: got %+v, want embedded JavaScript", html)
+	}
+	if public.Excluded[ClassVendored] == nil {
+		t.Fatal("vendored file not excluded")
+	}
+
+	if back := public.internal(); !reflect.DeepEqual(back.Languages, stats.Languages) ||
+		!reflect.DeepEqual(back.Files, stats.Files) || !reflect.DeepEqual(back.Excluded, stats.Excluded) {
+		t.Errorf("internal(newCodeStats(s)) differs from s:\ngot  %+v\nwant %+v", back.Files, stats.Files)
+	}
+	if again := newCodeStats(public.internal()); !reflect.DeepEqual(again, public) {
+		t.Errorf("newCodeStats(internal(s)) differs from s:\ngot  %+v\nwant %+v", again, public)
+	}
+}
+
+func TestNilCodeStats(t *testing.T) {
+	stats := newCodeStats(codeStatsFixture(t))
+
+	diff := DiffCodeStats(nil, stats)
+	for _, f := range diff.Files {
+		if !f.Added {
+			t.Errorf("%s: want added when diffing against nil", f.Path)
+		}
+	}
+	if len(diff.Languages) != len(stats.Languages) {
+		t.Errorf("got %d changed languages, want %d", len(diff.Languages), len(stats.Languages))
+	}
+	if diff := DiffCodeStats(stats, nil); len(diff.Files) == 0 || !diff.Files[0].Removed {
+		t.Errorf("diffing to nil: got %+v, want removed files", diff.Files)
+	}
+
+	if _, err := CountAuthors(context.Background(), ".", nil); err == nil {
+		t.Error("CountAuthors with nil stats: expected an error")
+	}
+}
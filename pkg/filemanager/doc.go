@@ -0,0 +1,26 @@
+// Package filemanager — публичный API инструмента file-manager.
+//
+// Все операции принимают context.Context и функциональные опции:
+//
+//	duplicates, err := filemanager.FindDuplicates(ctx, "/data",
+//		filemanager.WithIgnore(".git", "node_modules"),
+//		filemanager.WithHashAlgorithm(filemanager.HashSHA256),
+//	)
+//
+// Результаты возвращаются в типах этого пакета; псевдонимами внутренних типов
+// остаются только интерфейсы, ошибки и перечисления (ScanErrors, Matcher,
+// HashAlgorithm, FileClass).
+//
+// По умолчанию недоступные файлы пропускаются: результат возвращается вместе
+// с ошибкой типа ScanErrors, которую можно получить через errors.As.
+// WithStrict включает остановку на первой ошибке. При отмене ctx операции
+// возвращают частичный результат вместе с ctx.Err().
+//
+// У каждой операции есть вариант с суффиксом FS, который обходит произвольную
+// io/fs.FS: zip-архив, embed.FS или fstest.MapFS. Пути в результатах — имена
+// внутри неё. Символические ссылки различаются, только если fsys реализует LstatFS.
+//
+//	stats, err := filemanager.CountCodeLinesFS(ctx, fstest.MapFS{
+//		"main.go": {Data: []byte("package main\n")},
+//	})
+package filemanager
@@ -0,0 +1,226 @@
+package filemanager
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"sync"
+
+	"github.com/SHCDevelops/file-manager/internal/filesystem"
+	"github.com/SHCDevelops/file-manager/internal/git"
+)
+
+type (
+	ScanError     = filesystem.ScanError
+	ScanErrors    = filesystem.ScanErrors
+	HashAlgorithm = filesystem.HashAlgorithm
+	LstatFS       = filesystem.LstatFS
+)
+
+const (
+	HashMD5    = filesystem.HashMD5
+	HashSHA1   = filesystem.HashSHA1
+	HashSHA256 = filesystem.HashSHA256
+	HashSHA512 = filesystem.HashSHA512
+)
+
+// DirFS возвращает fs.FS для директории на диске с поддержкой LstatFS.
+func DirFS(dir string) fs.FS {
+	return filesystem.DirFS(dir)
+}
+
+// FindDuplicates возвращает группы файлов с одинаковым содержимым.
+func FindDuplicates(ctx context.Context, dir string, opts ...Option) ([][]string, error) {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	return filesystem.FindDuplicates(ctx, dir, o.scan)
+}
+
+// FindDuplicatesFS работает как FindDuplicates, но обходит fsys.
+func FindDuplicatesFS(ctx context.Context, fsys fs.FS, opts ...Option) ([][]string, error) {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	return filesystem.FindDuplicatesFS(ctx, fsys, o.scan)
+}
+
+// FileSize — файл и его размер в байтах.
+type FileSize struct {
+	Path string
+	Size int64
+}
+
+func newFileSizes(files []filesystem.FileSize) []FileSize {
+	if files == nil {
+		return nil
+	}
+	result := make([]FileSize, len(files))
+	for i, f := range files {
+		result[i] = FileSize(f)
+	}
+	return result
+}
+
+// AnalyzeSpace возвращает top самых больших файлов по убыванию размера.
+func AnalyzeSpace(ctx context.Context, dir string, top int, opts ...Option) ([]FileSize, error) {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	files, err := filesystem.AnalyzeSpace(ctx, dir, top, o.scan)
+	return newFileSizes(files), err
+}
+
+// AnalyzeSpaceFS работает как AnalyzeSpace, но обходит fsys.
+func AnalyzeSpaceFS(ctx context.Context, fsys fs.FS, top int, opts ...Option) ([]FileSize, error) {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	files, err := filesystem.AnalyzeSpaceFS(ctx, fsys, top, o.scan)
+	return newFileSizes(files), err
+}
+
+// CountCodeLines считает строки кода и комментариев по языкам.
+func CountCodeLines(ctx context.Context, dir string, opts ...Option) (*CodeStats, error) {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	stats, err := filesystem.CountCodeLines(ctx, dir, o.ignoreLanguages, o.scan)
+	return newCodeStats(stats), err
+}
+
+// CountCodeLinesFS работает как CountCodeLines, но обходит fsys.
+func CountCodeLinesFS(ctx context.Context, fsys fs.FS, opts ...Option) (*CodeStats, error) {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	stats, err := filesystem.CountCodeLinesFS(ctx, fsys, o.ignoreLanguages, o.scan)
+	return newCodeStats(stats), err
+}
+
+// CountAuthors приписывает строки из stats авторам по данным git blame;
+// stats должен быть посчитан для той же директории dir.
+func CountAuthors(ctx context.Context, dir string, stats *CodeStats, opts ...Option) ([]AuthorStat, error) {
+	if stats == nil {
+		return nil, errors.New("code stats are nil")
+	}
+	o := newOptions(opts)
+	defer o.startProgress()()
+	authors, err := filesystem.CountAuthors(ctx, dir, stats.internal(), o.scan)
+	return newAuthors(authors), err
+}
+
+// CodeStatsHistory считает строки кода в каждом коммите, выбранном history,
+// от старых к новым. dir должна находиться внутри git-репозитория.
+func CodeStatsHistory(ctx context.Context, dir string, history HistoryOptions, opts ...Option) ([]Revision, error) {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	revisions, err := filesystem.CodeStatsHistory(ctx, dir, history.internal(), o.ignoreLanguages, o.scan)
+	return newRevisions(revisions), err
+}
+
+// CountCodeLinesAt работает как CountCodeLines для ревизии rev git-репозитория
+// (коммит, ветка или тег): учитываются файлы этой ревизии внутри dir.
+func CountCodeLinesAt(ctx context.Context, dir, rev string, opts ...Option) (*CodeStats, error) {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	stats, err := filesystem.CountCodeLinesAt(ctx, dir, rev, o.ignoreLanguages, o.scan)
+	return newCodeStats(stats), err
+}
+
+// FindTodos ищет в комментариях пометки с тегами tags; при пустом tags —
+// DefaultTodoTags().
+func FindTodos(ctx context.Context, dir string, tags []string, opts ...Option) ([]Todo, error) {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	todos, err := filesystem.FindTodos(ctx, dir, tags, o.ignoreLanguages, o.scan)
+	return newTodos(todos), err
+}
+
+// FindTodosFS работает как FindTodos, но обходит fsys.
+func FindTodosFS(ctx context.Context, fsys fs.FS, tags []string, opts ...Option) ([]Todo, error) {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	todos, err := filesystem.FindTodosFS(ctx, fsys, tags, o.ignoreLanguages, o.scan)
+	return newTodos(todos), err
+}
+
+// FindClones ищет повторяющиеся фрагменты кода не короче minLines строк
+// (при minLines <= 0 — DefaultCloneLines) внутри файлов и между ними.
+func FindClones(ctx context.Context, dir string, minLines int, opts ...Option) ([]Clone, error) {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	clones, err := filesystem.FindClones(ctx, dir, minLines, o.ignoreLanguages, o.scan)
+	return newClones(clones), err
+}
+
+// FindClonesFS работает как FindClones, но обходит fsys.
+func FindClonesFS(ctx context.Context, fsys fs.FS, minLines int, opts ...Option) ([]Clone, error) {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	clones, err := filesystem.FindClonesFS(ctx, fsys, minLines, o.ignoreLanguages, o.scan)
+	return newClones(clones), err
+}
+
+// FindLicenses распознаёт лицензии файлов LICENSE и заголовков файлов
+// исходного кода по тегу SPDX-License-Identifier или по тексту лицензии.
+func FindLicenses(ctx context.Context, dir string, opts ...Option) (*LicenseReport, error) {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	report, err := filesystem.FindLicenses(ctx, dir, o.ignoreLanguages, o.scan)
+	return newLicenseReport(report), err
+}
+
+// FindLicensesFS работает как FindLicenses, но обходит fsys.
+func FindLicensesFS(ctx context.Context, fsys fs.FS, opts ...Option) (*LicenseReport, error) {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	report, err := filesystem.FindLicensesFS(ctx, fsys, o.ignoreLanguages, o.scan)
+	return newLicenseReport(report), err
+}
+
+// DiffCodeStats сравнивает две статистики по языкам и по файлам; nil
+// считается пустой статистикой.
+func DiffCodeStats(before, after *CodeStats) *CodeStatsDiff {
+	return newCodeStatsDiff(filesystem.DiffCodeStats(before.internal(), after.internal()))
+}
+
+// GitTracked возвращает фильтр для WithInclude, который оставляет только файлы
+// из индекса git-репозитория (пути — относительно dir).
+func GitTracked(ctx context.Context, dir string) (func(path string, isDir bool) bool, error) {
+	tracked, err := git.TrackedFiles(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	return tracked.Include, nil
+}
+
+// HashFile возвращает хеш содержимого файла в hex (md5, если не задан WithHashAlgorithm).
+func HashFile(ctx context.Context, path string, opts ...Option) (string, error) {
+	o := newOptions(opts)
+	return filesystem.HashFile(ctx, path, o.scan.HashAlgorithm)
+}
+
+// HashFileFS работает как HashFile для файла name внутри fsys.
+func HashFileFS(ctx context.Context, fsys fs.FS, name string, opts ...Option) (string, error) {
+	o := newOptions(opts)
+	return filesystem.HashFileFS(ctx, fsys, name, o.scan.HashAlgorithm)
+}
+
+// WatchSpace показывает top самых больших файлов и обновляет его по мере изменений
+// в dir (только Linux). fn вызывается после первого сканирования и после каждой
+// порции изменений. Работает до отмены ctx и возвращает ctx.Err().
+func WatchSpace(ctx context.Context, dir string, top int, fn func([]FileSize), opts ...Option) error {
+	o := newOptions(opts)
+	stopProgress := sync.OnceFunc(o.startProgress())
+	defer stopProgress()
+	return filesystem.WatchSpace(ctx, dir, top, o.scan, func(files []filesystem.FileSize) {
+		stopProgress()
+		fn(newFileSizes(files))
+	})
+}
+
+// WatchCodeStats работает как WatchSpace для CountCodeLines.
+func WatchCodeStats(ctx context.Context, dir string, fn func(*CodeStats), opts ...Option) error {
+	o := newOptions(opts)
+	stopProgress := sync.OnceFunc(o.startProgress())
+	defer stopProgress()
+	return filesystem.WatchCodeStats(ctx, dir, o.ignoreLanguages, o.scan, func(stats *filesystem.CodeStats) {
+		stopProgress()
+		fn(newCodeStats(stats))
+	})
+}
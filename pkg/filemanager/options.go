@@ -0,0 +1,157 @@
+package filemanager
+
+import (
+	"strings"
+	"time"
+
+	"github.com/SHCDevelops/file-manager/internal/filesystem"
+	"github.com/SHCDevelops/file-manager/internal/progress"
+)
+
+type Option func(*options)
+
+// Progress — снимок прогресса операции.
+type Progress struct {
+	Phase      string
+	Files      int64
+	Bytes      int64
+	TotalBytes int64
+	Elapsed    time.Duration
+	// Throughput — байт в секунду в текущей фазе.
+	Throughput float64
+	// ETA известен, только если задан объём фазы (TotalBytes > 0).
+	ETA time.Duration
+}
+
+type options struct {
+	scan             filesystem.ScanOptions
+	ignoreLanguages  []string
+	onProgress       func(Progress)
+	progressInterval time.Duration
+
+	predicate  filesystem.Predicate
+	follow     bool
+	maxResults int
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{progressInterval: time.Second}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// startProgress подключает счётчик, если задан WithProgress. Возвращённую
+// функцию нужно вызвать по завершении операции: она отдаёт итоговый снимок.
+func (o *options) startProgress() func() {
+	if o.onProgress == nil {
+		return func() {}
+	}
+	counter := progress.NewCounter()
+	o.scan.Progress = counter
+	report := func(s progress.Snapshot) { o.onProgress(Progress(s)) }
+	stop := progress.Watch(counter, o.progressInterval, report)
+	return func() {
+		stop()
+		report(counter.Snapshot())
+	}
+}
+
+// WithIgnore добавляет шаблоны в стиле .gitignore для пропускаемых путей;
+// шаблоны сравниваются с путём от сканируемой директории.
+func WithIgnore(patterns ...string) Option {
+	return func(o *options) {
+		o.scan.IgnoreList = append(o.scan.IgnoreList, patterns...)
+	}
+}
+
+// WithStrict прерывает операцию на первой недоступной записи.
+func WithStrict(strict bool) Option {
+	return func(o *options) {
+		o.scan.Strict = strict
+	}
+}
+
+// WithConcurrency задаёт число файлов, обрабатываемых параллельно.
+func WithConcurrency(n int) Option {
+	return func(o *options) {
+		o.scan.Concurrency = n
+	}
+}
+
+// WithHashAlgorithm выбирает хеш для FindDuplicates и HashFile.
+func WithHashAlgorithm(algorithm HashAlgorithm) Option {
+	return func(o *options) {
+		o.scan.HashAlgorithm = algorithm
+	}
+}
+
+// WithArchives включает обход содержимого zip, tar и tar.gz. Пути внутри
+// архива имеют вид "bundle.zip!/src/main.go". Tar и вложенные zip читаются в память
+// (до 256 MiB на архив) и остаются в ней до конца операции.
+func WithArchives(archives bool) Option {
+	return func(o *options) {
+		o.scan.Archives = archives
+	}
+}
+
+// WithInclude оставляет только записи, для которых fn вернул true. Путь передаётся
+// относительно сканируемой директории со слешами; если fn отклонил директорию,
+// в неё не заходят.
+func WithInclude(fn func(path string, isDir bool) bool) Option {
+	return func(o *options) {
+		o.scan.Include = fn
+	}
+}
+
+// WithIncludeGenerated учитывает в CountCodeLines сгенерированные и минифицированные
+// файлы и lock-файлы (package-lock.json, pnpm-lock.yaml). По умолчанию они попадают в CodeStats.Excluded.
+func WithIncludeGenerated(include bool) Option {
+	return func(o *options) {
+		o.scan.IncludeGenerated = include
+	}
+}
+
+// WithIncludeVendored учитывает в CountCodeLines сторонний код (vendor/, node_modules/
+// и т.п.). По умолчанию он попадает в CodeStats.Excluded.
+func WithIncludeVendored(include bool) Option {
+	return func(o *options) {
+		o.scan.IncludeVendored = include
+	}
+}
+
+// WithComplexity добавляет в CountCodeLines метрики функций Go: число функций,
+// цикломатическую сложность и глубину вложенности (см. CodeStats.TopFunctions).
+func WithComplexity(complexity bool) Option {
+	return func(o *options) {
+		o.scan.Complexity = complexity
+	}
+}
+
+// WithIgnoreLanguages исключает языки из CountCodeLines (без учёта регистра).
+func WithIgnoreLanguages(languages ...string) Option {
+	return func(o *options) {
+		for _, lang := range languages {
+			o.ignoreLanguages = append(o.ignoreLanguages, strings.ToLower(lang))
+		}
+	}
+}
+
+// WithProgress вызывает fn со снимком прогресса каждые interval и один раз
+// по завершении операции. fn вызывается из отдельной горутины.
+func WithProgress(interval time.Duration, fn func(Progress)) Option {
+	return func(o *options) {
+		if interval > 0 {
+			o.progressInterval = interval
+		}
+		o.onProgress = fn
+	}
+}
+
+// WithOnError вызывает fn для каждой пропущенной записи по мере сканирования.
+func WithOnError(fn func(*ScanError)) Option {
+	return func(o *options) {
+		o.scan.OnError = fn
+	}
+}
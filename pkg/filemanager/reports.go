@@ -0,0 +1,162 @@
+package filemanager
+
+import (
+	"slices"
+
+	"github.com/SHCDevelops/file-manager/internal/filesystem"
+)
+
+// DefaultCloneLines — минимальный размер клона в строках кода, если FindClones
+// передан minLines <= 0.
+const DefaultCloneLines = filesystem.DefaultCloneLines
+
+// DefaultTodoTags возвращает пометки, которые ищет FindTodos, если теги не заданы.
+func DefaultTodoTags() []string {
+	return slices.Clone(filesystem.DefaultTodoTags)
+}
+
+// Todo — пометка в комментарии: тег, необязательный автор в скобках после
+// него и текст до конца строки.
+type Todo struct {
+	Path string
+	// Line — номер строки с 1.
+	Line   int
+	Tag    string
+	Author string
+	Text   string
+}
+
+// CloneLocation — место, где встречается повторённый фрагмент.
+type CloneLocation struct {
+	Path string
+	// StartLine и EndLine — первая и последняя строка фрагмента в файле (с 1).
+	StartLine int
+	EndLine   int
+	// FirstCodeLine и LastCodeLine — те же границы в строках кода файла без
+	// комментариев и пустых строк (с 1): по ним TotalDuplicatedLines находит
+	// вложенные клоны. 0, если границы неизвестны.
+	FirstCodeLine int
+	LastCodeLine  int
+}
+
+// Clone — фрагмент кода, повторённый в нескольких местах. Более короткий
+// клон может лежать внутри более длинного, если часть фрагмента встречается
+// ещё где-то; общий итог по таким клонам считает TotalDuplicatedLines.
+type Clone struct {
+	// Lines — длина фрагмента в строках кода без комментариев и пустых строк.
+	Lines     int
+	Locations []CloneLocation
+	// Truncated — фрагмент повторяется слишком часто, и сравнивались только
+	// первые вхождения: часть копий может не попасть в Locations.
+	Truncated bool
+}
+
+// DuplicatedLines — строки кода, которые можно убрать, оставив одну копию.
+func (c Clone) DuplicatedLines() int {
+	if len(c.Locations) < 2 {
+		return 0
+	}
+	return c.Lines * (len(c.Locations) - 1)
+}
+
+// TotalDuplicatedLines возвращает число строк кода, которые можно убрать из
+// всех клонов, оставив в каждом первую копию. Строка, входящая в несколько
+// вложенных клонов, считается один раз. Копии без FirstCodeLine и
+// LastCodeLine добавляют Lines строк без проверки пересечений.
+func TotalDuplicatedLines(clones []Clone) int {
+	return filesystem.TotalDuplicatedLines(internalClones(clones))
+}
+
+// FileLicense — лицензия файла исходного кода или файла LICENSE.
+type FileLicense struct {
+	Path string
+	// License — SPDX-выражение из заголовка или распознанная по тексту
+	// лицензия; пусто, если лицензия не найдена.
+	License string
+	// Governing — лицензии ближайших файлов LICENSE в директории файла или выше.
+	Governing string
+	// Conflict — лицензия в заголовке не совпадает ни с одной из Governing.
+	Conflict bool
+}
+
+// LicenseReport — результат FindLicenses.
+type LicenseReport struct {
+	LicenseFiles []FileLicense
+	Files        []FileLicense
+}
+
+// Missing возвращает файлы исходного кода без лицензии в заголовке.
+func (r *LicenseReport) Missing() []FileLicense {
+	var missing []FileLicense
+	for _, f := range r.Files {
+		if f.License == "" {
+			missing = append(missing, f)
+		}
+	}
+	return missing
+}
+
+// Conflicts возвращает файлы, лицензия которых расходится с файлами LICENSE.
+func (r *LicenseReport) Conflicts() []FileLicense {
+	var conflicts []FileLicense
+	for _, f := range r.Files {
+		if f.Conflict {
+			conflicts = append(conflicts, f)
+		}
+	}
+	return conflicts
+}
+
+// Summary возвращает число файлов исходного кода по лицензиям; файлы без
+// лицензии учитываются под пустым ключом.
+func (r *LicenseReport) Summary() map[string]int {
+	summary := make(map[string]int)
+	for _, f := range r.Files {
+		summary[f.License]++
+	}
+	return summary
+}
+
+func newTodos(todos []filesystem.Todo) []Todo {
+	result := make([]Todo, len(todos))
+	for i, todo := range todos {
+		result[i] = Todo(todo)
+	}
+	return result
+}
+
+func newClones(clones []filesystem.Clone) []Clone {
+	result := make([]Clone, len(clones))
+	for i, clone := range clones {
+		result[i] = Clone{Lines: clone.Lines, Locations: make([]CloneLocation, len(clone.Locations)), Truncated: clone.Truncated}
+		for j, loc := range clone.Locations {
+			result[i].Locations[j] = CloneLocation(loc)
+		}
+	}
+	return result
+}
+
+func internalClones(clones []Clone) []filesystem.Clone {
+	result := make([]filesystem.Clone, len(clones))
+	for i, clone := range clones {
+		result[i] = filesystem.Clone{Lines: clone.Lines, Locations: make([]filesystem.CloneLocation, len(clone.Locations)), Truncated: clone.Truncated}
+		for j, loc := range clone.Locations {
+			result[i].Locations[j] = filesystem.CloneLocation(loc)
+		}
+	}
+	return result
+}
+
+func newLicenseReport(report *filesystem.LicenseReport) *LicenseReport {
+	if report == nil {
+		return nil
+	}
+	convert := func(files []filesystem.FileLicense) []FileLicense {
+		result := make([]FileLicense, len(files))
+		for i, f := range files {
+			result[i] = FileLicense{Path: f.Path, License: f.License, Governing: f.Governing, Conflict: f.Conflict}
+		}
+		return result
+	}
+	return &LicenseReport{LicenseFiles: convert(report.LicenseFiles), Files: convert(report.Files)}
+}
@@ -0,0 +1,155 @@
+package filemanager
+
+import (
+	"context"
+	"io/fs"
+	"time"
+
+	"github.com/SHCDevelops/file-manager/internal/filesystem"
+)
+
+type (
+	Matcher       = filesystem.Matcher
+	MatchMode     = filesystem.MatchMode
+	Predicate     = filesystem.Predicate
+	PredicateFunc = filesystem.PredicateFunc
+	EntryType     = filesystem.EntryType
+)
+
+type MatchOptions struct {
+	Mode       MatchMode
+	IgnoreCase bool
+	// FullPath сравнивает шаблон с путём от корня поиска, а не с именем.
+	FullPath bool
+}
+
+type SearchResult struct {
+	Path    string
+	Type    EntryType
+	Size    int64
+	ModTime time.Time
+	// Score — релевантность при MatchFuzzy.
+	Score int
+}
+
+const (
+	MatchGlob  = filesystem.MatchGlob
+	MatchRegex = filesystem.MatchRegex
+	MatchFuzzy = filesystem.MatchFuzzy
+
+	EntryFile    = filesystem.EntryFile
+	EntryDir     = filesystem.EntryDir
+	EntrySymlink = filesystem.EntrySymlink
+	EntryOther   = filesystem.EntryOther
+)
+
+// NewMatcher собирает Matcher из шаблонов, объединённых через ИЛИ.
+func NewMatcher(patterns []string, opts MatchOptions) (Matcher, error) {
+	return filesystem.NewMatcher(patterns, filesystem.MatchOptions(opts))
+}
+
+// NewPredicate создаёт предикат по имени: type, size, mtime, perm, owner, empty, newer.
+func NewPredicate(name, value string) (Predicate, error) {
+	return filesystem.NewPredicate(name, value)
+}
+
+// ParsePredicate разбирает выражение вида "type=f and (size=+10M or not empty)".
+func ParsePredicate(expr string) (Predicate, error) {
+	return filesystem.ParsePredicate(expr)
+}
+
+func And(preds ...Predicate) Predicate { return filesystem.And(preds...) }
+func Or(preds ...Predicate) Predicate  { return filesystem.Or(preds...) }
+func Not(pred Predicate) Predicate     { return filesystem.Not(pred) }
+
+// WithPredicate оставляет в результатах поиска только записи, для которых pred истинен.
+func WithPredicate(pred Predicate) Option {
+	return func(o *options) {
+		o.predicate = pred
+	}
+}
+
+// WithFollow включает переход по символическим ссылкам при поиске.
+func WithFollow(follow bool) Option {
+	return func(o *options) {
+		o.follow = follow
+	}
+}
+
+// WithMaxResults останавливает поиск после n результатов.
+func WithMaxResults(n int) Option {
+	return func(o *options) {
+		o.maxResults = n
+	}
+}
+
+func (o *options) searchOptions(matcher Matcher) filesystem.SearchOptions {
+	return filesystem.SearchOptions{
+		ScanOptions: o.scan,
+		Matcher:     matcher,
+		Predicate:   o.predicate,
+		Follow:      o.follow,
+		MaxResults:  o.maxResults,
+	}
+}
+
+// StreamSearch вызывает fn для каждой найденной записи в порядке обхода.
+// Если fn вернёт filepath.SkipAll, поиск завершится без ошибки.
+func StreamSearch(ctx context.Context, dir string, matcher Matcher, fn func(SearchResult) error, opts ...Option) error {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	return filesystem.StreamSearch(ctx, dir, o.searchOptions(matcher), streamResult(fn))
+}
+
+// StreamSearchFS работает как StreamSearch, но обходит fsys.
+func StreamSearchFS(ctx context.Context, fsys fs.FS, matcher Matcher, fn func(SearchResult) error, opts ...Option) error {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	return filesystem.StreamSearchFS(ctx, fsys, o.searchOptions(matcher), streamResult(fn))
+}
+
+// Search возвращает все найденные записи в порядке обхода.
+func Search(ctx context.Context, dir string, matcher Matcher, opts ...Option) ([]SearchResult, error) {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	results, err := filesystem.SearchFiles(ctx, dir, o.searchOptions(matcher))
+	return newSearchResults(results), err
+}
+
+// SearchFS работает как Search, но обходит fsys.
+func SearchFS(ctx context.Context, fsys fs.FS, matcher Matcher, opts ...Option) ([]SearchResult, error) {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	results, err := filesystem.SearchFilesFS(ctx, fsys, o.searchOptions(matcher))
+	return newSearchResults(results), err
+}
+
+// SortSearchResults сортирует результаты по path, size, mtime или score.
+func SortSearchResults(results []SearchResult, by string) error {
+	sorted := make([]filesystem.SearchResult, len(results))
+	for i, result := range results {
+		sorted[i] = filesystem.SearchResult(result)
+	}
+	if err := filesystem.SortSearchResults(sorted, by); err != nil {
+		return err
+	}
+	copy(results, newSearchResults(sorted))
+	return nil
+}
+
+func streamResult(fn func(SearchResult) error) func(filesystem.SearchResult) error {
+	return func(result filesystem.SearchResult) error {
+		return fn(SearchResult(result))
+	}
+}
+
+func newSearchResults(results []filesystem.SearchResult) []SearchResult {
+	if results == nil {
+		return nil
+	}
+	converted := make([]SearchResult, len(results))
+	for i, result := range results {
+		converted[i] = SearchResult(result)
+	}
+	return converted
+}
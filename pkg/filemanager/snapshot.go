@@ -0,0 +1,171 @@
+package filemanager
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/SHCDevelops/file-manager/internal/filesystem"
+)
+
+// Snapshot — состояние дерева файлов для сравнения с DiffSnapshots.
+type Snapshot struct {
+	Version int
+	Root    string
+	Created time.Time
+	// HashAlgorithm пуст, если хеши не считались.
+	HashAlgorithm HashAlgorithm
+	// IgnoreList и Archives — параметры сканирования; с ними же снимок
+	// сравнивается с текущим состоянием дерева.
+	IgnoreList []string
+	Archives   bool
+	// Entries отсортированы по Path.
+	Entries []SnapshotEntry
+	// Skipped — пути записей, которые не удалось прочитать. DiffSnapshots
+	// не сравнивает ни их, ни то, что лежит внутри.
+	Skipped []string
+}
+
+type SnapshotEntry struct {
+	// Path — путь от корня снимка со слешами.
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Mode    fs.FileMode
+	// Hash пуст, если хеши не считались или файл не удалось прочитать.
+	Hash string
+}
+
+type EntryChange struct {
+	Old, New SnapshotEntry
+}
+
+type DirGrowth struct {
+	Path             string
+	OldSize, NewSize int64
+}
+
+func (g DirGrowth) Delta() int64 {
+	return g.NewSize - g.OldSize
+}
+
+type SnapshotDiff struct {
+	Added    []SnapshotEntry
+	Removed  []SnapshotEntry
+	Modified []EntryChange
+	Moved    []EntryChange
+	// Dirs — директории, размер которых изменился, по убыванию |Delta|.
+	Dirs []DirGrowth
+}
+
+func (d *SnapshotDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0 && len(d.Moved) == 0
+}
+
+// TakeSnapshot сохраняет пути, размеры, время изменения и права файлов в dir.
+// Хеши содержимого считаются, только если задан WithHashAlgorithm.
+func TakeSnapshot(ctx context.Context, dir string, opts ...Option) (*Snapshot, error) {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	snapshot, err := filesystem.TakeSnapshot(ctx, dir, o.scan.HashAlgorithm, o.scan)
+	return newSnapshot(snapshot), err
+}
+
+// TakeSnapshotFS работает как TakeSnapshot, но обходит fsys.
+func TakeSnapshotFS(ctx context.Context, fsys fs.FS, opts ...Option) (*Snapshot, error) {
+	o := newOptions(opts)
+	defer o.startProgress()()
+	snapshot, err := filesystem.TakeSnapshotFS(ctx, fsys, o.scan.HashAlgorithm, o.scan)
+	return newSnapshot(snapshot), err
+}
+
+// WriteSnapshot сохраняет снимок в сжатом бинарном виде.
+func WriteSnapshot(w io.Writer, snapshot *Snapshot) error {
+	return filesystem.WriteSnapshot(w, snapshot.internal())
+}
+
+// ReadSnapshot читает снимок, сохранённый WriteSnapshot.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	snapshot, err := filesystem.ReadSnapshot(r)
+	return newSnapshot(snapshot), err
+}
+
+// DiffSnapshots возвращает добавленные, удалённые, изменённые и перемещённые
+// файлы, а также изменение размера директорий. Пути из Skipped обоих снимков
+// не сравниваются.
+func DiffSnapshots(before, after *Snapshot) (*SnapshotDiff, error) {
+	diff, err := filesystem.DiffSnapshots(before.internal(), after.internal())
+	if err != nil {
+		return nil, err
+	}
+	return newSnapshotDiff(diff), nil
+}
+
+func newSnapshot(snapshot *filesystem.Snapshot) *Snapshot {
+	if snapshot == nil {
+		return nil
+	}
+	result := &Snapshot{
+		Version:       snapshot.Version,
+		Root:          snapshot.Root,
+		Created:       snapshot.Created,
+		HashAlgorithm: snapshot.HashAlgorithm,
+		IgnoreList:    snapshot.IgnoreList,
+		Archives:      snapshot.Archives,
+		Entries:       make([]SnapshotEntry, len(snapshot.Entries)),
+		Skipped:       snapshot.Skipped,
+	}
+	for i, e := range snapshot.Entries {
+		result.Entries[i] = SnapshotEntry(e)
+	}
+	return result
+}
+
+// internal возвращает снимок во внутреннем формате; nil — пустой снимок.
+func (s *Snapshot) internal() *filesystem.Snapshot {
+	if s == nil {
+		return &filesystem.Snapshot{}
+	}
+	snapshot := &filesystem.Snapshot{
+		Version:       s.Version,
+		Root:          s.Root,
+		Created:       s.Created,
+		HashAlgorithm: s.HashAlgorithm,
+		IgnoreList:    s.IgnoreList,
+		Archives:      s.Archives,
+		Entries:       make([]filesystem.SnapshotEntry, len(s.Entries)),
+		Skipped:       s.Skipped,
+	}
+	for i, e := range s.Entries {
+		snapshot.Entries[i] = filesystem.SnapshotEntry(e)
+	}
+	return snapshot
+}
+
+func newSnapshotDiff(diff *filesystem.SnapshotDiff) *SnapshotDiff {
+	entries := func(list []filesystem.SnapshotEntry) []SnapshotEntry {
+		var result []SnapshotEntry
+		for _, e := range list {
+			result = append(result, SnapshotEntry(e))
+		}
+		return result
+	}
+	changes := func(list []filesystem.EntryChange) []EntryChange {
+		var result []EntryChange
+		for _, c := range list {
+			result = append(result, EntryChange{Old: SnapshotEntry(c.Old), New: SnapshotEntry(c.New)})
+		}
+		return result
+	}
+	result := &SnapshotDiff{
+		Added:    entries(diff.Added),
+		Removed:  entries(diff.Removed),
+		Modified: changes(diff.Modified),
+		Moved:    changes(diff.Moved),
+	}
+	for _, g := range diff.Dirs {
+		result.Dirs = append(result.Dirs, DirGrowth(g))
+	}
+	return result
+}
@@ -0,0 +1,38 @@
+package filemanager
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSnapshotWriteRead(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":     {Data: []byte("a")},
+		"dir/b.txt": {Data: []byte("bb")},
+	}
+	snapshot, err := TakeSnapshotFS(context.Background(), fsys, WithHashAlgorithm(HashSHA256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read.Entries, snapshot.Entries) || read.HashAlgorithm != HashSHA256 {
+		t.Errorf("got %+v, want %+v", read, snapshot)
+	}
+	diff, err := DiffSnapshots(snapshot, read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.Empty() {
+		t.Errorf("snapshot differs from itself after a round trip: %+v", diff)
+	}
+}